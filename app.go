@@ -6,9 +6,23 @@ import (
 )
 
 type App struct {
-    ID      string   `json:"app_id"`
-    Name    string   `json:"name"`
-    Runtime *Runtime `json:"runtime"`
+    ID          string       `json:"app_id"`
+    Name        string       `json:"name"`
+    Runtime     *Runtime     `json:"runtime"`
+    PackageID   string       `json:"package_id"`
+    ReleaseName string       `json:"release_name"`
+    Metadata    *AppMetadata `json:"metadata"`
+}
+
+type AppMetadata struct {
+    Tags []map[string]string `json:"tags"`
+}
+
+func (a *App) tags() []map[string]string {
+    if a.Metadata == nil {
+        return nil
+    }
+    return a.Metadata.Tags
 }
 
 func (c *Client) CreateCfApp(appName, pid, release, instanceType string, port int) (string, error) {
@@ -128,6 +142,18 @@ func (c *Client) SetAppAutoDelpoy(id string) (bool, error) {
 }
 
 func (c *Client) CreateSrApp(appName, pid, release, nodeName string, ports map[int]int) (string, error) {
+    return c.createSrApp(appName, pid, release, NodePlacement(nodeName), ports)
+}
+
+func (c *Client) CreateSrAppWithPlacement(appName, pid, release string, p *Placement, ports map[int]int) (string, error) {
+    if _, err := c.ValidatePlacement(p); err != nil {
+        return "", err
+    }
+
+    return c.createSrApp(appName, pid, release, p, ports)
+}
+
+func (c *Client) createSrApp(appName, pid, release string, p *Placement, ports map[int]int) (string, error) {
     type Port struct {
         ContainerPort int `json:"container_port"`
         HostPort int `json:"host_port"`
@@ -142,6 +168,7 @@ func (c *Client) CreateSrApp(appName, pid, release, nodeName string, ports map[i
         ContainerPorts []*Port `json:"container_ports"`
         ContainerRestart string `json:"container_restart"`
         ContainerPrivileged bool `json:"container_privileged"`
+        Strategy string `json:"scheduling_strategy,omitempty"`
     }
 
     type SrApp struct {
@@ -157,20 +184,21 @@ func (c *Client) CreateSrApp(appName, pid, release, nodeName string, ports map[i
     m := new(Metadata)
     m.Command = ""
     m.ContainerVolumes = make([]string, 0)
-    m.Tags = []map[string]string{{"name": nodeName}}
+    m.Tags = p.tags()
     m.ContainerPorts = make([]*Port, 0)
     for k, v := range ports {
         m.ContainerPorts = append(m.ContainerPorts, &Port{ContainerPort: k, HostPort: v, Protocol: "tcp", Published: true})
     }
     m.ContainerRestart = "always"
     m.ContainerPrivileged = false
+    m.Strategy = p.strategy()
 
     app := new(SrApp)
     app.Name = appName
     app.RuntimeID = "srsrsrsrsrsrsrsrsrsrsrsrsrsrsrsrsrsrsrsrsrsr"
     app.PackageID = pid
     app.ReleaseName = release
-    app.Instances = 1
+    app.Instances = p.instances()
     app.EnvVar = make(map[string]string)
     app.Metadata = m
 
@@ -274,6 +302,18 @@ func (c *Client) StopApp(id string) error {
     return nil
 }
 
+func (c *Client) RestartApp(id string) error {
+    status, _, _, err := c.do("POST", fmt.Sprintf("/v1/apps/%s/actions/restart", id), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d", status)
+    }
+
+    return nil
+}
+
 func (c *Client) DeleteApp(id string) error {
     status, _, _, err := c.do("DELETE", fmt.Sprintf("/v1/apps/%s", id), nil, nil, false)
     if err != nil {
@@ -0,0 +1,186 @@
+package dao
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+type Token struct {
+    AccessToken  string
+    Expiry       time.Time
+    RefreshToken string
+}
+
+// Credentials obtain access tokens for a Client. Authenticate is called with
+// the client locked, so it must not use Client methods sending API requests.
+type Credentials interface {
+    Authenticate(c *Client) (*Token, error)
+}
+
+// Refresher is implemented by Credentials able to renew a token they issued
+// from its refresh token. Refresh is called with the client locked too.
+type Refresher interface {
+    Refresh(c *Client, refreshToken string) (*Token, error)
+}
+
+var errNoRefresh = fmt.Errorf("credentials cannot refresh tokens")
+
+// StaticToken is a personal API token used as is.
+type StaticToken string
+
+func (t StaticToken) Authenticate(c *Client) (*Token, error) {
+    return &Token{AccessToken: string(t)}, nil
+}
+
+// PasswordCredentials log in with an email or mobile and a password, through
+// the public API or, with Internal set, the internal API which needs
+// InternalHost and InternalToken.
+type PasswordCredentials struct {
+    Username string
+    Password string
+    Internal bool
+}
+
+func (p *PasswordCredentials) Authenticate(c *Client) (*Token, error) {
+    type Info struct {
+        Username string `json:"email_or_mobile"`
+        Password string `json:"password"`
+    }
+
+    info := new(Info)
+    info.Username = p.Username
+    info.Password = p.Password
+
+    inbody, err := json.Marshal(info)
+    if err != nil {
+        return nil, err
+    }
+
+    url := "/v1/access-token"
+    if p.Internal {
+        url = "/internal/access-token"
+    }
+
+    status, outbody, _, err := c.send("POST", url, nil, inbody, p.Internal, "")
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    result := new(accessToken)
+    if err := json.Unmarshal(outbody, result); err != nil {
+        return nil, err
+    }
+
+    return result.toToken(), nil
+}
+
+// Refresh renews a token through the internal API. The public API has no
+// refresh endpoint, so public logins are renewed by logging in again.
+func (p *PasswordCredentials) Refresh(c *Client, refreshToken string) (*Token, error) {
+    if !p.Internal {
+        return nil, errNoRefresh
+    }
+
+    inbody, err := json.Marshal(map[string]string{"refresh_token": refreshToken})
+    if err != nil {
+        return nil, err
+    }
+
+    status, outbody, _, err := c.send("POST", "/internal/access-token/refresh", nil, inbody, true, "")
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    result := new(accessToken)
+    if err := json.Unmarshal(outbody, result); err != nil {
+        return nil, err
+    }
+
+    return result.toToken(), nil
+}
+
+// ClientCredentials use the OAuth2 client credentials grant. TokenURL
+// defaults to /oauth/token on the client Host.
+type ClientCredentials struct {
+    TokenURL     string
+    ClientID     string
+    ClientSecret string
+    Scopes       []string
+}
+
+func (cc *ClientCredentials) Authenticate(c *Client) (*Token, error) {
+    form := url.Values{}
+    form.Set("grant_type", "client_credentials")
+    if len(cc.Scopes) > 0 {
+        form.Set("scope", strings.Join(cc.Scopes, " "))
+    }
+
+    return cc.requestToken(c, form)
+}
+
+// Refresh uses the OAuth2 refresh token grant of the token endpoint.
+func (cc *ClientCredentials) Refresh(c *Client, refreshToken string) (*Token, error) {
+    form := url.Values{}
+    form.Set("grant_type", "refresh_token")
+    form.Set("refresh_token", refreshToken)
+
+    return cc.requestToken(c, form)
+}
+
+func (cc *ClientCredentials) requestToken(c *Client, form url.Values) (*Token, error) {
+    link := cc.TokenURL
+    if link == "" {
+        link = fmt.Sprintf("http://%s/oauth/token", c.Host)
+    }
+
+    req, err := http.NewRequest("POST", link, strings.NewReader(form.Encode()))
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+    req.Header.Set("Accept", "application/json")
+    req.SetBasicAuth(url.QueryEscape(cc.ClientID), url.QueryEscape(cc.ClientSecret))
+
+    client := &http.Client{Timeout: 10 * time.Second}
+    res, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer res.Body.Close()
+
+    outbody, err := ioutil.ReadAll(res.Body)
+    if err != nil {
+        return nil, err
+    }
+    if res.StatusCode/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+    }
+
+    result := struct {
+        accessToken
+        TokenType string `json:"token_type"`
+    } {}
+    if err := json.Unmarshal(outbody, &result); err != nil {
+        return nil, err
+    }
+    if result.Token == "" {
+        return nil, fmt.Errorf("token endpoint returned no access token")
+    }
+
+    token := result.toToken()
+    if strings.ToLower(result.TokenType) == "bearer" {
+        token.AccessToken = "Bearer " + token.AccessToken
+    }
+    return token, nil
+}
@@ -0,0 +1,168 @@
+package dao
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+)
+
+// oauthServer is a token endpoint with an API accepting the last token
+// issued. Refresh grants fail while refreshFails is set.
+type oauthServer struct {
+    *httptest.Server
+
+    mu           sync.Mutex
+    grants       []string
+    issued       int
+    valid        string
+    refreshFails bool
+}
+
+func newOAuthServer(t *testing.T) *oauthServer {
+    srv := new(oauthServer)
+    srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        srv.mu.Lock()
+        defer srv.mu.Unlock()
+
+        switch r.URL.Path {
+        case "/oauth/token":
+            if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+                w.WriteHeader(http.StatusUnauthorized)
+                return
+            }
+            grant := r.PostFormValue("grant_type")
+            srv.grants = append(srv.grants, grant)
+            if grant == "refresh_token" && (srv.refreshFails || r.PostFormValue("refresh_token") != "refresh-1") {
+                w.WriteHeader(http.StatusBadRequest)
+                fmt.Fprint(w, `{"error":"invalid_grant"}`)
+                return
+            }
+
+            srv.issued++
+            srv.valid = fmt.Sprintf("Bearer token-%d", srv.issued)
+            refresh := ""
+            if grant == "client_credentials" {
+                refresh = fmt.Sprintf("refresh-%d", srv.issued)
+            }
+            fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","refresh_token":%q}`, srv.issued, refresh)
+        case "/v1/runtimes":
+            if r.Header.Get("Authorization") != srv.valid {
+                w.WriteHeader(http.StatusUnauthorized)
+                return
+            }
+            fmt.Fprint(w, `{"runtimes":[]}`)
+        default:
+            t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+            w.WriteHeader(http.StatusNotFound)
+        }
+    }))
+    return srv
+}
+
+func (srv *oauthServer) expire() {
+    srv.mu.Lock()
+    defer srv.mu.Unlock()
+    srv.valid = "expired"
+}
+
+func (srv *oauthServer) grantList() string {
+    srv.mu.Lock()
+    defer srv.mu.Unlock()
+    return strings.Join(srv.grants, ",")
+}
+
+func TestClientCredentialsRefresh(t *testing.T) {
+    srv := newOAuthServer(t)
+    defer srv.Close()
+
+    // InternalHost is unreachable, so a refresh sent there would fail.
+    c := &Client{
+        Host:         strings.TrimPrefix(srv.URL, "http://"),
+        InternalHost: "127.0.0.1:1",
+        Credentials:  &ClientCredentials{ClientID: "client", ClientSecret: "secret"},
+    }
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatal(err)
+    }
+    if c.AuthToken != "Bearer token-1" {
+        t.Errorf("token = %s, want Bearer token-1", c.AuthToken)
+    }
+
+    srv.expire()
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatal(err)
+    }
+    if got := srv.grantList(); got != "client_credentials,refresh_token" {
+        t.Errorf("grants = %s, want client_credentials,refresh_token", got)
+    }
+    if c.refreshToken != "refresh-1" {
+        t.Errorf("refresh token = %q, want refresh-1 kept", c.refreshToken)
+    }
+}
+
+func TestRefreshFallsBackToAuthenticate(t *testing.T) {
+    srv := newOAuthServer(t)
+    defer srv.Close()
+
+    c := &Client{
+        Host:        strings.TrimPrefix(srv.URL, "http://"),
+        Credentials: &ClientCredentials{ClientID: "client", ClientSecret: "secret"},
+    }
+    if err := c.Authenticate(); err != nil {
+        t.Fatal(err)
+    }
+
+    srv.mu.Lock()
+    srv.refreshFails = true
+    srv.mu.Unlock()
+    srv.expire()
+
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatal(err)
+    }
+    if got := srv.grantList(); got != "client_credentials,refresh_token,client_credentials" {
+        t.Errorf("grants = %s, want client_credentials,refresh_token,client_credentials", got)
+    }
+    if c.refreshToken != "refresh-2" {
+        t.Errorf("refresh token = %q, want the one of the new login", c.refreshToken)
+    }
+}
+
+func TestPublicPasswordRenewsByLogin(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    // The test server fails on any request to the internal refresh
+    // endpoint.
+    c := ts.client()
+    c.Credentials = &PasswordCredentials{Username: "user", Password: "passwd"}
+    if err := c.Authenticate(); err != nil {
+        t.Fatal(err)
+    }
+    c.refreshToken = "refresh"
+
+    ts.expire()
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatal(err)
+    }
+    if n := ts.loginCount(); n != 2 {
+        t.Errorf("%d logins, want 2", n)
+    }
+}
+
+func TestUseCredentialsDropsRefreshToken(t *testing.T) {
+    c := &Client{Credentials: &ClientCredentials{}, refreshToken: "refresh"}
+    c.UseCredentials(&PasswordCredentials{Username: "user", Password: "passwd"})
+    if c.refreshToken != "" {
+        t.Errorf("refresh token %q kept after UseCredentials", c.refreshToken)
+    }
+
+    c.refreshToken = "refresh"
+    c.SetCredentials("user", "passwd")
+    if c.refreshToken != "" {
+        t.Errorf("refresh token %q kept after SetCredentials", c.refreshToken)
+    }
+}
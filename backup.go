@@ -0,0 +1,150 @@
+package dao
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/ioutil"
+)
+
+type Backup struct {
+    ID         string `json:"backup_id"`
+    InstanceID string `json:"service_instance_id"`
+    Status     string `json:"status"`
+    Size       int64  `json:"size"`
+    CreatedAt  int64  `json:"created_at"`
+}
+
+type BackupSchedule struct {
+    Enabled   bool   `json:"enabled"`
+    Cron      string `json:"cron"`
+    Retention int    `json:"retention"`
+}
+
+func (c *Client) CreateBackup(instanceID string) (string, error) {
+    status, outbody, _, err := c.do("POST", fmt.Sprintf("/v1/service-instances/%s/backups", instanceID), nil, nil, false)
+    if err != nil {
+        return "", err
+    }
+    if status/100 != 2 {
+        return "", fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    result := struct {
+        BackupID string `json:"backup_id"`
+    } {}
+    if err := json.Unmarshal(outbody, &result); err != nil {
+        return "", err
+    }
+
+    return result.BackupID, nil
+}
+
+func (c *Client) ListBackups(instanceID string) ([]*Backup, error) {
+    status, body, _, err := c.do("GET", fmt.Sprintf("/v1/service-instances/%s/backups", instanceID), nil, nil, false)
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d", status)
+    }
+
+    result := struct {
+        Backups []*Backup `json:"backups"`
+    } {}
+    if err := json.Unmarshal(body, &result); err != nil {
+        return nil, err
+    }
+
+    return result.Backups, nil
+}
+
+func (c *Client) GetBackup(instanceID, backupID string) (*Backup, error) {
+    backups, err := c.ListBackups(instanceID)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, b := range backups {
+        if b.ID == backupID {
+            return b, nil
+        }
+    }
+
+    return nil, nil
+}
+
+func (c *Client) DeleteBackup(instanceID, backupID string) error {
+    status, _, _, err := c.do("DELETE", fmt.Sprintf("/v1/service-instances/%s/backups/%s", instanceID, backupID), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d", status)
+    }
+
+    return nil
+}
+
+// DownloadBackup streams the backup archive to w without buffering it.
+func (c *Client) DownloadBackup(instanceID, backupID string, w io.Writer) error {
+    res, err := c.stream("GET", fmt.Sprintf("/v1/service-instances/%s/backups/%s/download", instanceID, backupID), nil)
+    if err != nil {
+        return err
+    }
+    defer res.Body.Close()
+
+    if res.StatusCode/100 != 2 {
+        outbody, _ := ioutil.ReadAll(io.LimitReader(res.Body, 4096))
+        return fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+    }
+
+    _, err = io.Copy(w, res.Body)
+    return err
+}
+
+func (c *Client) RestoreBackup(instanceID, backupID string) error {
+    status, outbody, _, err := c.do("POST", fmt.Sprintf("/v1/service-instances/%s/backups/%s/actions/restore", instanceID, backupID), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+func (c *Client) GetBackupSchedule(instanceID string) (*BackupSchedule, error) {
+    status, body, _, err := c.do("GET", fmt.Sprintf("/v1/service-instances/%s/backup_schedule", instanceID), nil, nil, false)
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d", status)
+    }
+
+    result := new(BackupSchedule)
+    if err := json.Unmarshal(body, result); err != nil {
+        return nil, err
+    }
+
+    return result, nil
+}
+
+func (c *Client) SetBackupSchedule(instanceID string, schedule *BackupSchedule) error {
+    inbody, err := json.Marshal(schedule)
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("PUT", fmt.Sprintf("/v1/service-instances/%s/backup_schedule", instanceID), nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
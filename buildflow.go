@@ -3,12 +3,24 @@ package dao
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 )
 
 type Project struct {
-	ID        string `json:"buildflow_id"`
-	Name      string `json:"name"`
-	PackageID string `package_id`
+	ID             string       `json:"buildflow_id"`
+	Name           string       `json:"name"`
+	PackageID      string       `json:"package_id"`
+	Package        *Package     `json:"package"`
+	Provider       string       `json:"repo_provider"`
+	RepoName       string       `json:"repo_name"`
+	RepoURL        string       `json:"repo_url"`
+	DefaultBranch  string       `json:"default_branch"`
+	DockerfilePath string       `json:"dockerfile_path"`
+	BuildContext   string       `json:"build_context"`
+	AutoBuild      bool         `json:"auto_build"`
+	Rules          []*BuildRule `json:"rules"`
+	CreatedAt      int64        `json:"created_at"`
 }
 
 type Buildflow struct {
@@ -17,23 +29,51 @@ type Buildflow struct {
 }
 
 type Build struct {
-	ID            int    `json:"id"`
-	Status        string `json:"status"`
-	Sha           string `json:"sha"`
-	Ref 	      string `json:"ref"`
-	Tag           string `json:"tag"`
-	TriggerMethod string `json:"trigger_method"`
-	CreatedAt     int64  `json:"created_at"`
-	Message       string `json:"message"`
+	ID            int           `json:"id"`
+	Status        string        `json:"status"`
+	Sha           string        `json:"sha"`
+	Ref           string        `json:"ref"`
+	Tag           string        `json:"tag"`
+	TriggerMethod string        `json:"trigger_method"`
+	CreatedAt     int64         `json:"created_at"`
+	StartedAt     int64         `json:"started_at"`
+	FinishedAt    int64         `json:"finished_at"`
+	Message       string        `json:"message"`
+	Stages        []*BuildStage `json:"stages"`
+	PackageID     string        `json:"package_id"`
+	ReleaseName   string        `json:"release_name"`
+	ImageDigest   string        `json:"image_digest"`
 }
 
-type CiBuild struct {
-	ID        int    `json:"id"`
-	Status    string `json:"status"`
-	Sha       string `json:"sha"`
-	Ref 	  string `json:"ref"`
-	CreatedAt int64  `json:"created_at"`
-	Message   string `json:"message"`
+type BuildStage struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	StartedAt  int64  `json:"started_at"`
+	FinishedAt int64  `json:"finished_at"`
+}
+
+type BuildflowOptions struct {
+	Name           string `json:"name,omitempty"`
+	Provider       string `json:"repo_provider,omitempty"`
+	RepoName       string `json:"repo_name,omitempty"`
+	DockerfilePath string `json:"dockerfile_path,omitempty"`
+	BuildContext   string `json:"build_context,omitempty"`
+	PackageID      string `json:"package_id,omitempty"`
+}
+
+type BuildOptions struct {
+	Branch string            `json:"branch,omitempty"`
+	Tag    string            `json:"tag,omitempty"`
+	Sha    string            `json:"sha,omitempty"`
+	Args   map[string]string `json:"build_args,omitempty"`
+	Env    map[string]string `json:"env_vars,omitempty"`
+}
+
+type BuildRule struct {
+	ID        string `json:"rule_id,omitempty"`
+	Type      string `json:"type"`
+	Pattern   string `json:"pattern"`
+	AutoBuild bool   `json:"auto_build"`
 }
 
 func (c *Client) ListBuildflow() ([]*Buildflow, error) {
@@ -71,6 +111,22 @@ func (c *Client) GetBuildflowByName(name string) (*Buildflow, error) {
 	return nil, nil
 }
 
+func (c *Client) ListBuildflowByPackage(packageID string) ([]*Buildflow, error) {
+	bs, err := c.ListBuildflow()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Buildflow, 0)
+	for _, b := range bs {
+		if b.Project != nil && b.Project.PackageID == packageID {
+			result = append(result, b)
+		}
+	}
+
+	return result, nil
+}
+
 func (c *Client) GetBuildflow(id string) (*Buildflow, error) {
 	url := fmt.Sprintf("/v1/ship/project/%s", id)
 	status, body, _, err := c.do("GET", url, nil, nil, false)
@@ -90,12 +146,16 @@ func (c *Client) GetBuildflow(id string) (*Buildflow, error) {
 }
 
 func (c *Client) GetCiBuildByMessage(buildflowID, message string) (*Build, error) {
-	cibuilds, err := c.ListBuild(buildflowID)
+	return c.GetBuildByMessage(buildflowID, message)
+}
+
+func (c *Client) GetBuildByMessage(buildflowID, message string) (*Build, error) {
+	builds, err := c.ListBuild(buildflowID)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, b := range cibuilds {
+	for _, b := range builds {
 		if b.Message == message {
 			return b, nil
 		}
@@ -154,30 +214,298 @@ func (c *Client) GetBuildByTag(buildflowID, tag string) (*Build, error) {
 	return nil, nil
 }
 
+// GetBuildArtifact resolves a successful build to the package release it
+// produced, ready to be passed to RestageApp.
+func (c *Client) GetBuildArtifact(buildflowID string, id int) (*Release, error) {
+	b, err := c.GetBuild(buildflowID, id)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("build %d not found", id)
+	}
+	if !b.Succeeded() {
+		return nil, fmt.Errorf("build %d is %s", id, b.Status)
+	}
+
+	packageID := b.PackageID
+	if packageID == "" {
+		flow, err := c.GetBuildflow(buildflowID)
+		if err != nil {
+			return nil, err
+		}
+		if flow.Project != nil {
+			packageID = flow.Project.PackageID
+		}
+	}
+	if packageID == "" {
+		return nil, fmt.Errorf("buildflow %s has no package", buildflowID)
+	}
+
+	release := b.ReleaseName
+	if release == "" {
+		release = b.Tag
+	}
+	if release == "" {
+		return nil, fmt.Errorf("build %d produced no release", id)
+	}
+
+	r, err := c.GetRelease(packageID, release)
+	if err != nil {
+		return nil, err
+	}
+	r.PackageID = packageID
+
+	return r, nil
+}
+
 func (c *Client) PostManualBuild(buildflowID, branch string) (int, error) {
-	type BuildInfo struct {
-		Branch string `json:"branch"`
+	b, err := c.TriggerBuild(buildflowID, &BuildOptions{Branch: branch})
+	if err != nil {
+		return 0, err
+	}
+
+	return b.ID, nil
+}
+
+// TriggerBuild starts a build from a branch, a tag or a commit sha. Args are
+// passed as docker build arguments and Env overrides the build environment.
+func (c *Client) TriggerBuild(buildflowID string, opts *BuildOptions) (*Build, error) {
+	if opts.Branch == "" && opts.Tag == "" && opts.Sha == "" {
+		return nil, fmt.Errorf("build needs a branch, tag or sha")
+	}
+	if opts.Branch != "" && opts.Tag != "" {
+		return nil, fmt.Errorf("build cannot use both branch %s and tag %s", opts.Branch, opts.Tag)
 	}
 
-	bi := &BuildInfo{Branch: branch}
 	url := fmt.Sprintf("/v1/ship/project/%s/pipelines", buildflowID)
-	inbody, err := json.Marshal(bi)
+	inbody, err := json.Marshal(opts)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	status, outbody, _, err := c.do("POST", url, nil, inbody, false)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	if status/100 != 2 {
-		return 0, fmt.Errorf("Status code is %d, reason %s", status, outbody)
+		return nil, fmt.Errorf("Status code is %d, reason %s", status, outbody)
 	}
 
 	result := new(Build)
 	if err := json.Unmarshal(outbody, result); err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *Client) CancelBuild(buildflowID string, id int) error {
+	url := fmt.Sprintf("/v1/ship/project/%s/pipelines/%d/actions/cancel", buildflowID, id)
+	status, outbody, _, err := c.do("POST", url, nil, nil, false)
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+	}
+
+	return nil
+}
+
+func (c *Client) RetryBuild(buildflowID string, id int) (*Build, error) {
+	url := fmt.Sprintf("/v1/ship/project/%s/pipelines/%d/actions/retry", buildflowID, id)
+	status, outbody, _, err := c.do("POST", url, nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("Status code is %d, reason %s", status, outbody)
+	}
+
+	result := new(Build)
+	if err := json.Unmarshal(outbody, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (c *Client) CreateBuildflow(opts *BuildflowOptions) (string, error) {
+	inbody, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+
+	status, outbody, _, err := c.do("POST", "/v1/ship/projects", nil, inbody, false)
+	if err != nil {
+		return "", err
+	}
+	if status/100 != 2 {
+		return "", fmt.Errorf("Status code is %d, reason %s", status, outbody)
+	}
+
+	result := new(Project)
+	if err := json.Unmarshal(outbody, result); err != nil {
+		return "", err
 	}
 
 	return result.ID, nil
 }
+
+// UpdateBuildflow changes the fields set in opts and leaves the others as is.
+func (c *Client) UpdateBuildflow(id string, opts *BuildflowOptions) error {
+	inbody, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	return c.patchBuildflow(id, inbody)
+}
+
+func (c *Client) SetAutoBuild(id string, enabled bool) error {
+	inbody, err := json.Marshal(map[string]bool{"auto_build": enabled})
+	if err != nil {
+		return err
+	}
+
+	return c.patchBuildflow(id, inbody)
+}
+
+func (c *Client) patchBuildflow(id string, inbody []byte) error {
+	url := fmt.Sprintf("/v1/ship/project/%s", id)
+	status, outbody, _, err := c.do("PATCH", url, nil, inbody, false)
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+	}
+
+	return nil
+}
+
+func (c *Client) DeleteBuildflow(id string) error {
+	url := fmt.Sprintf("/v1/ship/project/%s", id)
+	status, _, _, err := c.do("DELETE", url, nil, nil, false)
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("Status code is %d", status)
+	}
+
+	return nil
+}
+
+func (c *Client) ListBuildRule(buildflowID string) ([]*BuildRule, error) {
+	url := fmt.Sprintf("/v1/ship/project/%s/rules", buildflowID)
+	status, body, _, err := c.do("GET", url, nil, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if status/100 != 2 {
+		return nil, fmt.Errorf("Status code is %d", status)
+	}
+
+	result := struct {
+		Rules []*BuildRule `json:"rules"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Rules, nil
+}
+
+func (c *Client) CreateBuildRule(buildflowID string, rule *BuildRule) (string, error) {
+	inbody, err := json.Marshal(rule)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf("/v1/ship/project/%s/rules", buildflowID)
+	status, outbody, _, err := c.do("POST", url, nil, inbody, false)
+	if err != nil {
+		return "", err
+	}
+	if status/100 != 2 {
+		return "", fmt.Errorf("Status code is %d, reason %s", status, outbody)
+	}
+
+	result := new(BuildRule)
+	if err := json.Unmarshal(outbody, result); err != nil {
+		return "", err
+	}
+
+	return result.ID, nil
+}
+
+func (c *Client) UpdateBuildRule(buildflowID string, rule *BuildRule) error {
+	inbody, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("/v1/ship/project/%s/rules/%s", buildflowID, rule.ID)
+	status, outbody, _, err := c.do("PUT", url, nil, inbody, false)
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+	}
+
+	return nil
+}
+
+func (c *Client) DeleteBuildRule(buildflowID, ruleID string) error {
+	url := fmt.Sprintf("/v1/ship/project/%s/rules/%s", buildflowID, ruleID)
+	status, _, _, err := c.do("DELETE", url, nil, nil, false)
+	if err != nil {
+		return err
+	}
+	if status/100 != 2 {
+		return fmt.Errorf("Status code is %d", status)
+	}
+
+	return nil
+}
+
+func (b *Build) Finished() bool {
+	switch strings.ToLower(b.Status) {
+	case "success", "succeeded", "passed", "failure", "failed", "error", "canceled", "cancelled", "timeout":
+		return true
+	}
+	return false
+}
+
+func (b *Build) Succeeded() bool {
+	switch strings.ToLower(b.Status) {
+	case "success", "succeeded", "passed":
+		return true
+	}
+	return false
+}
+
+// Duration is the time the build has been running, up to now for a build
+// that has not finished.
+func (b *Build) Duration() time.Duration {
+	if b.StartedAt == 0 {
+		return 0
+	}
+
+	end := time.Now().Unix()
+	if b.FinishedAt != 0 {
+		end = b.FinishedAt
+	}
+	return time.Duration(end-b.StartedAt) * time.Second
+}
+
+// QueueDuration is the time the build waited between creation and start.
+func (b *Build) QueueDuration() time.Duration {
+	if b.StartedAt == 0 || b.CreatedAt == 0 {
+		return 0
+	}
+	return time.Duration(b.StartedAt-b.CreatedAt) * time.Second
+}
@@ -0,0 +1,161 @@
+package dao
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxLogPages bounds the pages GetBuildLog reads, so a server ignoring the
+// offset cannot keep it collecting the same lines forever.
+const maxLogPages = 10000
+
+type LogLine struct {
+	Stage string `json:"stage"`
+	Step  string `json:"step"`
+	Text  string `json:"text"`
+}
+
+type BuildLog struct {
+	Lines []*LogLine `json:"lines"`
+}
+
+type LogStage struct {
+	Name  string     `json:"name"`
+	Steps []*LogStep `json:"steps"`
+}
+
+type LogStep struct {
+	Name  string   `json:"name"`
+	Lines []string `json:"lines"`
+}
+
+// Stages groups the log lines by stage and step, keeping their order.
+func (l *BuildLog) Stages() []*LogStage {
+	stages := make([]*LogStage, 0)
+	var stage *LogStage
+	var step *LogStep
+
+	for _, line := range l.Lines {
+		if stage == nil || stage.Name != line.Stage {
+			stage = &LogStage{Name: line.Stage, Steps: make([]*LogStep, 0)}
+			stages = append(stages, stage)
+			step = nil
+		}
+		if step == nil || step.Name != line.Step {
+			step = &LogStep{Name: line.Step, Lines: make([]string, 0)}
+			stage.Steps = append(stage.Steps, step)
+		}
+		step.Lines = append(step.Lines, line.Text)
+	}
+
+	return stages
+}
+
+// getBuildLogPage returns the lines from offset and the offset of the next
+// page, which is derived from the line count when the server does not report
+// it. A server reporting an offset that does not move past returned lines is
+// an error, since following it would repeat them.
+func (c *Client) getBuildLogPage(buildflowID string, id, offset int) ([]*LogLine, int, bool, error) {
+	url := fmt.Sprintf("/v1/ship/project/%s/pipelines/%d/logs?offset=%d", buildflowID, id, offset)
+	status, body, _, err := c.do("GET", url, nil, nil, false)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if status/100 != 2 {
+		return nil, 0, false, fmt.Errorf("Status code is %d, reason %s", status, body)
+	}
+
+	result := struct {
+		Lines      []*LogLine `json:"lines"`
+		NextOffset *int       `json:"next_offset"`
+		Finished   bool       `json:"finished"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, 0, false, err
+	}
+	next := offset + len(result.Lines)
+	if result.NextOffset != nil {
+		next = *result.NextOffset
+	}
+	if len(result.Lines) > 0 && next <= offset {
+		return nil, 0, false, fmt.Errorf("log of build %d of buildflow %s did not advance past offset %d", id, buildflowID, offset)
+	}
+
+	return result.Lines, next, result.Finished, nil
+}
+
+// GetBuildLog returns the log written so far, which is the full log once the
+// build has finished.
+func (c *Client) GetBuildLog(buildflowID string, id int) (*BuildLog, error) {
+	log := &BuildLog{Lines: make([]*LogLine, 0)}
+	offset := 0
+	for pages := 0; ; pages++ {
+		if pages == maxLogPages {
+			return nil, fmt.Errorf("log of build %d of buildflow %s exceeds %d pages, the server may ignore the log offset", id, buildflowID, maxLogPages)
+		}
+
+		lines, next, _, err := c.getBuildLogPage(buildflowID, id, offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(lines) == 0 {
+			return log, nil
+		}
+
+		log.Lines = append(log.Lines, lines...)
+		offset = next
+	}
+}
+
+// StreamBuildLog calls fn for every log line of the build, following it until
+// the build finishes. An error returned by fn stops the stream.
+func (c *Client) StreamBuildLog(buildflowID string, id int, fn func(*LogLine) error) error {
+	offset := 0
+	for {
+		lines, next, finished, err := c.getBuildLogPage(buildflowID, id, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+		offset = next
+
+		if len(lines) == 0 {
+			if finished {
+				return nil
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+// LogPrinter returns a StreamBuildLog callback writing lines to w, with a
+// header each time a new stage or step starts.
+func LogPrinter(w io.Writer) func(*LogLine) error {
+	stage, step := "", ""
+	started := false
+	return func(line *LogLine) error {
+		if !started || line.Stage != stage {
+			if _, err := fmt.Fprintf(w, "==> %s\n", line.Stage); err != nil {
+				return err
+			}
+			stage, step = line.Stage, ""
+		}
+		if !started || line.Step != step {
+			if _, err := fmt.Fprintf(w, "--> %s\n", line.Step); err != nil {
+				return err
+			}
+			step = line.Step
+		}
+		started = true
+
+		_, err := fmt.Fprintln(w, line.Text)
+		return err
+	}
+}
@@ -0,0 +1,142 @@
+package dao
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// logServer serves pages of the log lines; page returns the body for an
+// offset.
+func logServer(t *testing.T, page func(offset int) string) (*Client, func() int, func()) {
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/ship/project/f1/pipelines/7/logs" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		requests++
+		mu.Unlock()
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		fmt.Fprint(w, page(offset))
+	}))
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+	c := &Client{Host: strings.TrimPrefix(srv.URL, "http://"), AuthToken: "token"}
+	return c, count, srv.Close
+}
+
+func linesFrom(offset, total int) string {
+	lines := make([]string, 0)
+	for i := offset; i < total && i < offset+2; i++ {
+		lines = append(lines, fmt.Sprintf(`{"stage":"build","step":"make","text":"line %d"}`, i))
+	}
+	return "[" + strings.Join(lines, ",") + "]"
+}
+
+func TestGetBuildLogWithoutNextOffset(t *testing.T) {
+	c, requests, done := logServer(t, func(offset int) string {
+		return fmt.Sprintf(`{"lines":%s,"finished":true}`, linesFrom(offset, 5))
+	})
+	defer done()
+
+	log, err := c.GetBuildLog("f1", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log.Lines) != 5 || log.Lines[4].Text != "line 4" {
+		t.Errorf("got %d lines, want line 0 to line 4", len(log.Lines))
+	}
+	if n := requests(); n != 4 {
+		t.Errorf("%d requests, want 4", n)
+	}
+}
+
+func TestStreamBuildLogWithNextOffset(t *testing.T) {
+	c, _, done := logServer(t, func(offset int) string {
+		next := offset + 2
+		if next > 5 {
+			next = 5
+		}
+		return fmt.Sprintf(`{"lines":%s,"next_offset":%d,"finished":true}`, linesFrom(offset, 5), next)
+	})
+	defer done()
+
+	texts := make([]string, 0)
+	err := c.StreamBuildLog("f1", 7, func(line *LogLine) error {
+		texts = append(texts, line.Text)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 5 || texts[0] != "line 0" || texts[4] != "line 4" {
+		t.Errorf("streamed %q", texts)
+	}
+}
+
+func TestStreamBuildLogStuckOffset(t *testing.T) {
+	c, requests, done := logServer(t, func(offset int) string {
+		return fmt.Sprintf(`{"lines":%s,"next_offset":2}`, linesFrom(0, 5))
+	})
+	defer done()
+
+	streamed := 0
+	err := c.StreamBuildLog("f1", 7, func(*LogLine) error {
+		streamed++
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "did not advance") {
+		t.Errorf("error = %v, want the offset not advancing", err)
+	}
+	if n := requests(); n != 2 || streamed != 2 {
+		t.Errorf("%d requests and %d lines streamed, want 2 and 2", n, streamed)
+	}
+}
+
+func TestGetBuildLogIgnoredOffset(t *testing.T) {
+	c, requests, done := logServer(t, func(offset int) string {
+		return fmt.Sprintf(`{"lines":%s}`, linesFrom(0, 5))
+	})
+	defer done()
+
+	_, err := c.GetBuildLog("f1", 7)
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("error = %v, want the page cap", err)
+	}
+	if n := requests(); n != maxLogPages {
+		t.Errorf("%d requests, want %d", n, maxLogPages)
+	}
+}
+
+func TestBuildLogStages(t *testing.T) {
+	log := &BuildLog{Lines: []*LogLine{
+		{Stage: "build", Step: "clone", Text: "a"},
+		{Stage: "build", Step: "make", Text: "b"},
+		{Stage: "build", Step: "make", Text: "c"},
+		{Stage: "push", Step: "upload", Text: "d"},
+	}}
+
+	var out []string
+	for _, stage := range log.Stages() {
+		for _, step := range stage.Steps {
+			out = append(out, fmt.Sprintf("%s/%s:%s", stage.Name, step.Name, strings.Join(step.Lines, ",")))
+		}
+	}
+	want := "build/clone:a build/make:b,c push/upload:d"
+	if got := strings.Join(out, " "); got != want {
+		t.Errorf("Stages() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package dao
+
+import (
+	"sort"
+	"time"
+)
+
+type BuildStatistics struct {
+	From              time.Time                   `json:"from"`
+	To                time.Time                   `json:"to"`
+	Total             int                         `json:"total"`
+	Succeeded         int                         `json:"succeeded"`
+	Failed            int                         `json:"failed"`
+	Unfinished        int                         `json:"unfinished"`
+	SuccessRate       float64                     `json:"success_rate"`
+	MeanDuration      time.Duration               `json:"mean_duration"`
+	P50Duration       time.Duration               `json:"p50_duration"`
+	P90Duration       time.Duration               `json:"p90_duration"`
+	P99Duration       time.Duration               `json:"p99_duration"`
+	FailuresByBranch  map[string]int              `json:"failures_by_branch"`
+	FailuresByTrigger map[string]int              `json:"failures_by_trigger"`
+	BuildsPerDay      map[string]int              `json:"builds_per_day"`
+	ByProject         map[string]*BuildStatistics `json:"by_project,omitempty"`
+}
+
+// BuildStats computes statistics over the builds created in [from, to).
+// SuccessRate is relative to finished builds and durations only count
+// finished builds reporting both a start and a finish time. Days are keyed as
+// 2006-01-02 in UTC.
+func BuildStats(builds []*Build, from, to time.Time) *BuildStatistics {
+	s := new(BuildStatistics)
+	s.From = from
+	s.To = to
+	s.FailuresByBranch = make(map[string]int)
+	s.FailuresByTrigger = make(map[string]int)
+	s.BuildsPerDay = make(map[string]int)
+
+	durations := make([]time.Duration, 0)
+	for _, b := range builds {
+		created := time.Unix(b.CreatedAt, 0)
+		if created.Before(from) || !created.Before(to) {
+			continue
+		}
+
+		s.Total++
+		s.BuildsPerDay[created.UTC().Format("2006-01-02")]++
+
+		switch {
+		case !b.Finished():
+			s.Unfinished++
+			continue
+		case b.Succeeded():
+			s.Succeeded++
+		default:
+			s.Failed++
+			ref := b.Ref
+			if ref == "" {
+				ref = b.Tag
+			}
+			s.FailuresByBranch[ref]++
+			s.FailuresByTrigger[b.TriggerMethod]++
+		}
+
+		if b.StartedAt != 0 && b.FinishedAt != 0 {
+			durations = append(durations, b.Duration())
+		}
+	}
+
+	if finished := s.Succeeded + s.Failed; finished > 0 {
+		s.SuccessRate = float64(s.Succeeded) / float64(finished)
+	}
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+		var sum time.Duration
+		for _, d := range durations {
+			sum += d
+		}
+		s.MeanDuration = sum / time.Duration(len(durations))
+		s.P50Duration = percentile(durations, 50)
+		s.P90Duration = percentile(durations, 90)
+		s.P99Duration = percentile(durations, 99)
+	}
+
+	return s
+}
+
+// GetBuildStats computes BuildStats over the builds of every buildflow, with
+// per project statistics keyed by project name.
+func (c *Client) GetBuildStats(from, to time.Time) (*BuildStatistics, error) {
+	flows, err := c.ListBuildflow()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]*Build, 0)
+	byProject := make(map[string]*BuildStatistics)
+	for _, f := range flows {
+		if f.Project == nil {
+			continue
+		}
+
+		builds, err := c.ListBuild(f.Project.ID)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, builds...)
+		byProject[f.Project.Name] = BuildStats(builds, from, to)
+	}
+
+	s := BuildStats(all, from, to)
+	s.ByProject = byProject
+	return s, nil
+}
+
+// percentile uses the nearest-rank method on sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 0, 10)
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Second)
+	}
+
+	tests := []struct {
+		durations []time.Duration
+		p         int
+		want      time.Duration
+	}{
+		{sorted, 0, 1 * time.Second},
+		{sorted, 10, 1 * time.Second},
+		{sorted, 50, 5 * time.Second},
+		{sorted, 51, 6 * time.Second},
+		{sorted, 90, 9 * time.Second},
+		{sorted, 99, 10 * time.Second},
+		{sorted, 100, 10 * time.Second},
+		{sorted[:1], 50, 1 * time.Second},
+		{sorted[:1], 99, 1 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := percentile(tt.durations, tt.p); got != tt.want {
+			t.Errorf("percentile(%d durations, %d) = %s, want %s", len(tt.durations), tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestBuildStatsDurations(t *testing.T) {
+	day := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) int64 {
+		return day.Add(time.Duration(minutes) * time.Minute).Unix()
+	}
+
+	builds := []*Build{
+		{ID: 1, Status: "success", CreatedAt: at(0), StartedAt: at(1), FinishedAt: at(3)},
+		{ID: 2, Status: "failed", Ref: "main", TriggerMethod: "push", CreatedAt: at(10), StartedAt: at(10), FinishedAt: at(14)},
+		{ID: 3, Status: "success", CreatedAt: at(20), StartedAt: at(20)},
+		{ID: 4, Status: "running", CreatedAt: at(30), StartedAt: at(30)},
+		{ID: 5, Status: "success", CreatedAt: at(-60 * 24)},
+	}
+
+	s := BuildStats(builds, day, day.Add(24*time.Hour))
+
+	if s.Total != 4 || s.Succeeded != 2 || s.Failed != 1 || s.Unfinished != 1 {
+		t.Fatalf("counts = %d total, %d succeeded, %d failed, %d unfinished", s.Total, s.Succeeded, s.Failed, s.Unfinished)
+	}
+	if s.MeanDuration != 3*time.Minute {
+		t.Errorf("MeanDuration = %s, want 3m0s", s.MeanDuration)
+	}
+	if s.P99Duration != 4*time.Minute {
+		t.Errorf("P99Duration = %s, want 4m0s", s.P99Duration)
+	}
+	if s.FailuresByBranch["main"] != 1 || s.FailuresByTrigger["push"] != 1 {
+		t.Errorf("failures = %v by branch, %v by trigger", s.FailuresByBranch, s.FailuresByTrigger)
+	}
+	if s.BuildsPerDay["2026-03-02"] != 4 {
+		t.Errorf("BuildsPerDay = %v", s.BuildsPerDay)
+	}
+}
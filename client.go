@@ -7,14 +7,30 @@ import (
     "io"
     "io/ioutil"
     "net/http"
+    "sync"
     "time"
 )
 
+const pollInterval = 3 * time.Second
+
 type Client struct {
     Host string
     AuthToken string
     InternalHost string
     InternalToken string
+    RegistryHost string
+
+    // Credentials obtains AuthToken when it is empty or expired. Login sets
+    // password credentials; change them later with UseCredentials.
+    Credentials Credentials
+
+    // TokenExpiry is when AuthToken expires, zero if unknown. OnTokenRefresh
+    // is called after the client re-authenticated on its own.
+    TokenExpiry time.Time
+    OnTokenRefresh func(token string, expiry time.Time)
+
+    mu           sync.Mutex
+    refreshToken string
 }
 
 type Runtime struct {
@@ -34,41 +50,18 @@ func (c *Client) DeleteUser(user string) error {
 }
 
 func (c *Client) Login(user, passwd string) error {
-    type Info struct {
-        Username string `json:"email_or_mobile"`
-        Password string `json:"password"`
-    }
-
-    info := new(Info)
-    info.Username = user
-    info.Password = passwd
-
-    inbody, err := json.Marshal(info)
-    if err != nil {
-        return err
-    }
-
-    status, outbody, _, err := c.do("POST", "/internal/access-token", nil, inbody, true)
-    if err != nil {
-        return err
-    }
-    if status/100 != 2 {
-        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
-    }
-
-    result := struct {
-        Token string `json:"access_token"`
-    } {}
-    if err := json.Unmarshal(outbody, &result); err != nil {
-        return err
-    }
-
-    c.AuthToken = result.Token
-    return nil
+    c.SetCredentials(user, passwd)
+    return c.Authenticate()
 }
 
 func (c *Client) Logout() {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
     c.AuthToken = ""
+    c.TokenExpiry = time.Time{}
+    c.Credentials = nil
+    c.refreshToken = ""
 }
 
 func (c *Client) ListRuntime() ([]*Runtime, error) {
@@ -90,35 +83,39 @@ func (c *Client) ListRuntime() ([]*Runtime, error) {
     return result.Runtimes, nil
 }
 
+// do sends an API request. A missing token or one about to expire is
+// obtained first, and a request rejected with 401 is retried once after
+// re-authentication when the client has renewable credentials.
 func (c *Client) do(method, url string, header map[string]string, body []byte, internal bool) (int, []byte, map[string]string, error) {
-    var reader io.Reader = nil
-    if body != nil {
-        reader = bytes.NewBuffer(body)
+    if internal {
+        return c.send(method, url, header, body, true, c.token())
     }
 
-    client := &http.Client{Timeout: 10 * time.Second}
-    client.Transport = &http.Transport{DisableKeepAlives: true}
+    if c.needsToken() {
+        if err := c.refresh(c.token()); err != nil {
+            return 0, nil, nil, err
+        }
+    }
 
-    link := fmt.Sprintf("http://%s%s", c.Host, url)
-    if internal {
-        link = fmt.Sprintf("http://%s%s", c.InternalHost, url)
+    token := c.token()
+    status, outbody, resHeader, err := c.send(method, url, header, body, false, token)
+    if err != nil || status != http.StatusUnauthorized || !c.canRefresh() {
+        return status, outbody, resHeader, err
     }
 
-    req, err := http.NewRequest(method, link, reader)
-    if err != nil {
+    if err := c.refresh(token); err != nil {
         return 0, nil, nil, err
     }
+    return c.send(method, url, header, body, false, c.token())
+}
 
-    for k, v := range header {
-        req.Header.Set(k, v)
-    }
+func (c *Client) send(method, url string, header map[string]string, body []byte, internal bool, token string) (int, []byte, map[string]string, error) {
+    client := &http.Client{Timeout: 10 * time.Second}
+    client.Transport = &http.Transport{DisableKeepAlives: true}
 
-    req.Header.Set("Authorization", c.AuthToken)
-    req.Header.Set("Content-Type", "application/json")
-    if internal {
-        req.Header.Set("X-DAO-INTERNAL-TOKEN", c.InternalToken)
-    } else {
-        req.Header.Set("Authorization", c.AuthToken)
+    req, err := c.newRequest(method, url, header, body, internal, token)
+    if err != nil {
+        return 0, nil, nil, err
     }
 
     res, err := client.Do(req)
@@ -142,3 +139,68 @@ func (c *Client) do(method, url string, header map[string]string, body []byte, i
 
     return res.StatusCode, outbody, resHeader, nil
 }
+
+// stream is like do but leaves reading the response body to the caller and
+// has no overall timeout, for downloads of arbitrary size. It obtains and
+// renews the token the same way.
+func (c *Client) stream(method, url string, header map[string]string) (*http.Response, error) {
+    client := &http.Client{}
+    client.Transport = &http.Transport{DisableKeepAlives: true}
+
+    if c.needsToken() {
+        if err := c.refresh(c.token()); err != nil {
+            return nil, err
+        }
+    }
+
+    token := c.token()
+    req, err := c.newRequest(method, url, header, nil, false, token)
+    if err != nil {
+        return nil, err
+    }
+
+    res, err := client.Do(req)
+    if err != nil || res.StatusCode != http.StatusUnauthorized || !c.canRefresh() {
+        return res, err
+    }
+    res.Body.Close()
+
+    if err := c.refresh(token); err != nil {
+        return nil, err
+    }
+    if req, err = c.newRequest(method, url, header, nil, false, c.token()); err != nil {
+        return nil, err
+    }
+    return client.Do(req)
+}
+
+func (c *Client) newRequest(method, url string, header map[string]string, body []byte, internal bool, token string) (*http.Request, error) {
+    var reader io.Reader = nil
+    if body != nil {
+        reader = bytes.NewBuffer(body)
+    }
+
+    link := fmt.Sprintf("http://%s%s", c.Host, url)
+    if internal {
+        link = fmt.Sprintf("http://%s%s", c.InternalHost, url)
+    }
+
+    req, err := http.NewRequest(method, link, reader)
+    if err != nil {
+        return nil, err
+    }
+
+    for k, v := range header {
+        req.Header.Set(k, v)
+    }
+
+    req.Header.Set("Authorization", token)
+    req.Header.Set("Content-Type", "application/json")
+    if internal {
+        req.Header.Set("X-DAO-INTERNAL-TOKEN", c.InternalToken)
+    } else {
+        req.Header.Set("Authorization", token)
+    }
+
+    return req, nil
+}
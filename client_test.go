@@ -0,0 +1,221 @@
+package dao
+
+import (
+    "bytes"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// tokenServer issues a new token on every login and accepts only the last
+// one issued.
+type tokenServer struct {
+    *httptest.Server
+
+    mu        sync.Mutex
+    logins    int
+    valid     string
+    expiresIn int
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+    ts := new(tokenServer)
+    ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        ts.mu.Lock()
+        defer ts.mu.Unlock()
+
+        switch r.URL.Path {
+        case "/internal/access-token", "/v1/access-token":
+            ts.logins++
+            ts.valid = fmt.Sprintf("token-%d", ts.logins)
+            fmt.Fprintf(w, `{"access_token":%q,"expires_in":%d}`, ts.valid, ts.expiresIn)
+        case "/v1/runtimes", "/v1/service-instances/i1/backups/b1/download":
+            if r.Header.Get("Authorization") != ts.valid {
+                w.WriteHeader(http.StatusUnauthorized)
+                return
+            }
+            if strings.HasSuffix(r.URL.Path, "/download") {
+                fmt.Fprint(w, "archive")
+                return
+            }
+            fmt.Fprint(w, `{"runtimes":[]}`)
+        default:
+            t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+            w.WriteHeader(http.StatusNotFound)
+        }
+    }))
+    return ts
+}
+
+func (ts *tokenServer) client() *Client {
+    host := strings.TrimPrefix(ts.URL, "http://")
+    return &Client{Host: host, InternalHost: host}
+}
+
+func (ts *tokenServer) loginCount() int {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    return ts.logins
+}
+
+// expire makes the server reject every token issued so far.
+func (ts *tokenServer) expire() {
+    ts.mu.Lock()
+    defer ts.mu.Unlock()
+    ts.valid = "expired"
+}
+
+func TestClientLoginsOnFirstRequest(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    c := ts.client()
+    c.SetCredentials("user", "passwd")
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatal(err)
+    }
+    if n := ts.loginCount(); n != 1 {
+        t.Errorf("%d logins, want 1", n)
+    }
+}
+
+func TestClientRetriesAfter401(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    c := ts.client()
+    var refreshed []string
+    c.OnTokenRefresh = func(token string, expiry time.Time) {
+        refreshed = append(refreshed, token)
+    }
+    if err := c.Login("user", "passwd"); err != nil {
+        t.Fatal(err)
+    }
+
+    ts.expire()
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatalf("ListRuntime after expiry: %s", err)
+    }
+    if n := ts.loginCount(); n != 2 {
+        t.Errorf("%d logins, want 2", n)
+    }
+    if len(refreshed) != 1 || refreshed[0] != "token-2" || c.AuthToken != "token-2" {
+        t.Errorf("OnTokenRefresh got %v, client token %s, want [token-2]", refreshed, c.AuthToken)
+    }
+}
+
+func TestClientStreamRetriesAfter401(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    c := ts.client()
+    if err := c.Login("user", "passwd"); err != nil {
+        t.Fatal(err)
+    }
+
+    ts.expire()
+    buf := new(bytes.Buffer)
+    if err := c.DownloadBackup("i1", "b1", buf); err != nil {
+        t.Fatalf("DownloadBackup after expiry: %s", err)
+    }
+    if buf.String() != "archive" {
+        t.Errorf("downloaded %q, want archive", buf)
+    }
+}
+
+func TestClientRenewsBeforeExpiry(t *testing.T) {
+    ts := newTokenServer(t)
+    ts.expiresIn = 10
+    defer ts.Close()
+
+    c := ts.client()
+    if err := c.Login("user", "passwd"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := c.ListRuntime(); err != nil {
+        t.Fatal(err)
+    }
+
+    // The token expires within tokenExpiryMargin, so it is renewed before
+    // the request instead of after a 401.
+    if n := ts.loginCount(); n != 2 {
+        t.Errorf("%d logins, want 2", n)
+    }
+}
+
+func TestClientConcurrentRefresh(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    c := ts.client()
+    if err := c.Login("user", "passwd"); err != nil {
+        t.Fatal(err)
+    }
+
+    var mu sync.Mutex
+    callbacks := 0
+    c.OnTokenRefresh = func(string, time.Time) {
+        mu.Lock()
+        callbacks++
+        mu.Unlock()
+    }
+
+    ts.expire()
+    var wg sync.WaitGroup
+    errs := make(chan error, 20)
+    for i := 0; i < 20; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            if _, err := c.ListRuntime(); err != nil {
+                errs <- err
+            }
+        }()
+    }
+    wg.Wait()
+    close(errs)
+
+    for err := range errs {
+        t.Error(err)
+    }
+    if n := ts.loginCount(); n != 2 {
+        t.Errorf("%d logins, want 2", n)
+    }
+    if callbacks != 1 {
+        t.Errorf("OnTokenRefresh called %d times, want 1", callbacks)
+    }
+}
+
+func TestClientStaticTokenNotRenewed(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    c := ts.client()
+    c.Credentials = StaticToken("personal")
+    _, err := c.ListRuntime()
+    if err == nil || !strings.Contains(err.Error(), "Status code is 401") {
+        t.Errorf("ListRuntime error = %v, want a 401", err)
+    }
+    if n := ts.loginCount(); n != 0 {
+        t.Errorf("%d logins, want 0", n)
+    }
+}
+
+func TestClientWithoutCredentialsReturns401(t *testing.T) {
+    ts := newTokenServer(t)
+    defer ts.Close()
+
+    c := ts.client()
+    c.AuthToken = "obtained-elsewhere"
+    _, err := c.ListRuntime()
+    if err == nil || !strings.Contains(err.Error(), "Status code is 401") {
+        t.Errorf("ListRuntime error = %v, want a 401", err)
+    }
+    if n := ts.loginCount(); n != 0 {
+        t.Errorf("%d logins, want 0", n)
+    }
+}
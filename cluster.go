@@ -0,0 +1,130 @@
+package dao
+
+import (
+    "encoding/json"
+    "fmt"
+)
+
+func (c *Client) GetCluster(id string) (*Cluster, error) {
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, cluster := range cs {
+        if cluster.ID == id {
+            return cluster, nil
+        }
+    }
+
+    return nil, nil
+}
+
+func (c *Client) GetClusterByName(name string) (*Cluster, error) {
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, cluster := range cs {
+        if cluster.Name == name {
+            return cluster, nil
+        }
+    }
+
+    return nil, nil
+}
+
+func (c *Client) CreateCluster(name string) (string, error) {
+    type ClusterT struct {
+        Name string `json:"node_cluster_name"`
+    }
+
+    inbody, err := json.Marshal(&ClusterT{Name: name})
+    if err != nil {
+        return "", err
+    }
+
+    status, outbody, _, err := c.do("POST", "/v1/clusters", nil, inbody, false)
+    if err != nil {
+        return "", err
+    }
+    if status/100 != 2 {
+        return "", fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    result := struct {
+        ClusterID string `json:"node_cluster_id"`
+    } {}
+    if err := json.Unmarshal(outbody, &result); err != nil {
+        return "", err
+    }
+
+    return result.ClusterID, nil
+}
+
+func (c *Client) RenameCluster(id, name string) error {
+    type ClusterT struct {
+        Name string `json:"node_cluster_name"`
+    }
+
+    inbody, err := json.Marshal(&ClusterT{Name: name})
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("PATCH", fmt.Sprintf("/v1/clusters/%s", id), nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+func (c *Client) DeleteCluster(id string) error {
+    status, outbody, _, err := c.do("DELETE", fmt.Sprintf("/v1/clusters/%s", id), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+func (c *Client) SetDefaultCluster(id string) error {
+    status, outbody, _, err := c.do("POST", fmt.Sprintf("/v1/clusters/%s/actions/set_default", id), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+// RotateClusterToken invalidates the current Cluster.Token and returns the
+// new one. Import commands generated before the rotation stop working.
+func (c *Client) RotateClusterToken(id string) (string, error) {
+    status, outbody, _, err := c.do("POST", fmt.Sprintf("/v1/clusters/%s/actions/rotate_token", id), nil, nil, false)
+    if err != nil {
+        return "", err
+    }
+    if status/100 != 2 {
+        return "", fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    result := struct {
+        Token string `json:"suggest_token"`
+    } {}
+    if err := json.Unmarshal(outbody, &result); err != nil {
+        return "", err
+    }
+
+    return result.Token, nil
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/daocloud-hwu/dao"
+)
+
+var groups = map[string]map[string]*command{
+    "login": {
+        "": {"<user>", "log in with the password from $DAO_PASSWORD or stdin and save the access token", login},
+    },
+    "profiles": {
+        "list":        {"", "list config profiles", listProfiles},
+        "use":         {"<profile>", "make a profile the current one", useProfile},
+        "set-default": {"<output|cluster> [value]", "set a default of the profile, or clear it without value", setDefault},
+    },
+    "apps": {
+        "list":    {"", "list apps", listApps},
+        "get":     {"<app-id>", "show an app", getApp},
+        "state":   {"<app-id>", "show the state of an app", appState},
+        "start":   {"<app-id>", "start an app", idAction("app", "started", (*dao.Client).StartApp)},
+        "stop":    {"<app-id>", "stop an app", idAction("app", "stopped", (*dao.Client).StopApp)},
+        "restart": {"<app-id>", "restart an app", idAction("app", "restarted", (*dao.Client).RestartApp)},
+        "delete":  {"<app-id>", "delete an app", idAction("app", "deleted", (*dao.Client).DeleteApp)},
+    },
+    "stacks": {
+        "list":   {"", "list stacks", listStacks},
+        "state":  {"<stack-id>", "show the state of a stack", stackState},
+        "start":  {"<stack-id>", "start a stack", idAction("stack", "started", (*dao.Client).StartStack)},
+        "stop":   {"<stack-id>", "stop a stack", idAction("stack", "stopped", (*dao.Client).StopStack)},
+        "delete": {"<stack-id>", "delete a stack", idAction("stack", "deleted", (*dao.Client).DeleteStack)},
+    },
+    "services": {
+        "list":            {"", "list services", listServices},
+        "instances":       {"", "list service instances", listServiceInstances},
+        "create-instance": {"<service-id> <name> <type>", "create a service instance", createServiceInstance},
+        "delete-instance": {"<instance-id>", "delete a service instance", idAction("service instance", "deleted", (*dao.Client).DeleteServiceInstance)},
+    },
+    "packages": {
+        "list":     {"[daocloud]", "list packages, or the public daocloud ones", listPackages},
+        "releases": {"<package-id>", "list the releases of a package", listReleases},
+        "delete":   {"<package-id>", "delete a package", idAction("package", "deleted", (*dao.Client).DeletePackage)},
+    },
+    "builds": {
+        "flows":   {"", "list buildflows", listBuildflows},
+        "list":    {"<buildflow-id>", "list the builds of a buildflow", listBuilds},
+        "trigger": {"<buildflow-id> <branch>", "start a build from a branch", triggerBuild},
+        "cancel":  {"<buildflow-id> <build-id>", "cancel a build", cancelBuild},
+        "log":     {"<buildflow-id> <build-id>", "follow the log of a build", buildLog},
+    },
+    "nodes": {
+        "list":       {"", "list nodes of all clusters", listNodes},
+        "clusters":   {"", "list clusters", listClusters},
+        "delete":     {"<node-id>", "delete a node", idAction("node", "deleted", (*dao.Client).DeleteNode)},
+        "import-cmd": {"[cluster-id]", "print the command importing a host, into the default cluster if set", importCmd},
+    },
+}
+
+func idAction(kind, done string, fn func(*dao.Client, string) error) func(*env, []string) error {
+    return func(e *env, args []string) error {
+        if err := needArgs(args, 1); err != nil {
+            return err
+        }
+        if err := fn(e.client, args[0]); err != nil {
+            return err
+        }
+
+        e.out.message("%s %s %s", kind, args[0], done)
+        return nil
+    }
+}
+
+func login(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+    passwd, err := readPassword()
+    if err != nil {
+        return err
+    }
+    if err := e.client.Login(args[0], passwd); err != nil {
+        return err
+    }
+
+    p := e.config.Profile(e.profile)
+    if p == nil {
+        p = new(dao.Profile)
+    }
+    p.Host = e.client.Host
+    p.InternalHost = e.client.InternalHost
+    p.InternalToken = e.client.InternalToken
+    p.Token = e.client.AuthToken
+    e.config.SetProfile(e.profile, p)
+    if e.config.Current == "" {
+        e.config.Current = e.profile
+    }
+    if err := e.config.Save(e.path); err != nil {
+        return err
+    }
+
+    e.out.message("logged in, token saved to profile %s", e.profile)
+    return nil
+}
+
+// readPassword returns $DAO_PASSWORD, or the first line of stdin, prompting
+// for it with echo turned off when stdin is a terminal.
+func readPassword() (string, error) {
+    if passwd := os.Getenv("DAO_PASSWORD"); passwd != "" {
+        return passwd, nil
+    }
+
+    if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 && stty("-echo") == nil {
+        fmt.Fprint(os.Stderr, "Password: ")
+        defer func() {
+            stty("echo")
+            fmt.Fprintln(os.Stderr)
+        }()
+    }
+
+    line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+    if err != nil && err != io.EOF {
+        return "", fmt.Errorf("read password: %s", err)
+    }
+
+    passwd := strings.TrimRight(line, "\r\n")
+    if passwd == "" {
+        return "", fmt.Errorf("no password given on stdin or in $DAO_PASSWORD")
+    }
+    return passwd, nil
+}
+
+func stty(mode string) error {
+    cmd := exec.Command("stty", mode)
+    cmd.Stdin = os.Stdin
+    return cmd.Run()
+}
+
+func listProfiles(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    names := make([]string, 0, len(e.config.Profiles))
+    for name := range e.config.Profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    rows := make([][]string, 0, len(names))
+    for _, name := range names {
+        current := ""
+        if name == e.config.Current {
+            current = "*"
+        }
+        rows = append(rows, []string{current, name, e.config.Profiles[name].Host})
+    }
+
+    profiles := make(map[string]*dao.Profile)
+    for name, p := range e.config.Profiles {
+        profiles[name] = p.Redacted()
+    }
+    return e.out.print(profiles, []string{"CURRENT", "NAME", "HOST"}, rows)
+}
+
+func useProfile(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+    if _, ok := e.config.Profiles[args[0]]; !ok {
+        return fmt.Errorf("profile %s not found in %s", args[0], e.path)
+    }
+
+    e.config.Current = args[0]
+    if err := e.config.Save(e.path); err != nil {
+        return err
+    }
+
+    e.out.message("using profile %s", args[0])
+    return nil
+}
+
+func setDefault(e *env, args []string) error {
+    if len(args) != 1 && len(args) != 2 {
+        return needArgs(args, 2)
+    }
+
+    key, value := args[0], ""
+    if len(args) == 2 {
+        value = args[1]
+    }
+    switch key {
+    case "output":
+        if value != "" && value != "table" && value != "json" && value != "yaml" {
+            return &usageError{fmt.Sprintf("unknown output format %s", value)}
+        }
+    case "cluster":
+    default:
+        return &usageError{fmt.Sprintf("unknown default %s", key)}
+    }
+
+    p := e.config.Profile(e.profile)
+    if p == nil {
+        return fmt.Errorf("profile %s not found in %s", e.profile, e.path)
+    }
+    if value == "" {
+        delete(p.Defaults, key)
+    } else {
+        if p.Defaults == nil {
+            p.Defaults = make(map[string]string)
+        }
+        p.Defaults[key] = value
+    }
+    if err := e.config.Save(e.path); err != nil {
+        return err
+    }
+
+    if value == "" {
+        e.out.message("cleared default %s of profile %s", key, e.profile)
+    } else {
+        e.out.message("default %s of profile %s set to %s", key, e.profile, value)
+    }
+    return nil
+}
+
+func listApps(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    apps, err := e.client.ListApp()
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(apps))
+    for _, a := range apps {
+        runtime := ""
+        if a.Runtime != nil {
+            runtime = a.Runtime.Name
+        }
+        rows = append(rows, []string{a.ID, a.Name, runtime, a.ReleaseName})
+    }
+    return e.out.print(apps, []string{"ID", "NAME", "RUNTIME", "RELEASE"}, rows)
+}
+
+func getApp(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+
+    app, err := e.client.GetApp(args[0])
+    if err != nil {
+        return err
+    }
+    if app == nil {
+        return &notFoundError{"app", args[0]}
+    }
+
+    url, err := e.client.GetAppUrl(app.ID)
+    if err != nil {
+        return err
+    }
+
+    rows := [][]string{{app.ID, app.Name, app.PackageID, app.ReleaseName, url}}
+    return e.out.print(app, []string{"ID", "NAME", "PACKAGE", "RELEASE", "URL"}, rows)
+}
+
+func appState(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+
+    state, err := e.client.GetAppState(args[0])
+    if err != nil {
+        return err
+    }
+
+    v := map[string]string{"app_id": args[0], "state": state}
+    return e.out.print(v, nil, [][]string{{state}})
+}
+
+func listStacks(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    stacks, err := e.client.ListStack()
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(stacks))
+    for _, s := range stacks {
+        names := make([]string, 0, len(s.Apps))
+        for _, a := range s.Apps {
+            names = append(names, a.Name)
+        }
+        rows = append(rows, []string{s.ID, s.Name, strings.Join(names, ",")})
+    }
+    return e.out.print(stacks, []string{"ID", "NAME", "APPS"}, rows)
+}
+
+func stackState(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+
+    state, err := e.client.GetStackState(args[0])
+    if err != nil {
+        return err
+    }
+
+    v := map[string]string{"stack_id": args[0], "state": state}
+    return e.out.print(v, nil, [][]string{{state}})
+}
+
+func listServices(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    services, err := e.client.ListService()
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(services))
+    for _, s := range services {
+        rows = append(rows, []string{s.ID, s.Name, s.Category})
+    }
+    return e.out.print(services, []string{"ID", "NAME", "CATEGORY"}, rows)
+}
+
+func listServiceInstances(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    instances, err := e.client.ListServiceInstance()
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(instances))
+    for _, s := range instances {
+        rows = append(rows, []string{s.ID, s.Name, s.ServiceID, s.Type})
+    }
+    return e.out.print(instances, []string{"ID", "NAME", "SERVICE", "TYPE"}, rows)
+}
+
+func createServiceInstance(e *env, args []string) error {
+    if err := needArgs(args, 3); err != nil {
+        return err
+    }
+
+    id, err := e.client.CreateServiceInstance(args[0], args[1], args[2])
+    if err != nil {
+        return err
+    }
+
+    v := map[string]string{"service_instance_id": id}
+    return e.out.print(v, nil, [][]string{{id}})
+}
+
+func listPackages(e *env, args []string) error {
+    ptype := ""
+    if len(args) > 1 {
+        return needArgs(args, 1)
+    }
+    if len(args) == 1 {
+        ptype = args[0]
+    }
+
+    packages, err := e.client.ListPackage(ptype)
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(packages))
+    for _, p := range packages {
+        latest := ""
+        if p.LatestRelease != nil {
+            latest = p.LatestRelease.Name
+        }
+        rows = append(rows, []string{p.ID, p.Repository(), strconv.FormatBool(p.IsPublic), strconv.Itoa(p.ReleaseAccount), latest})
+    }
+    return e.out.print(packages, []string{"ID", "REPOSITORY", "PUBLIC", "RELEASES", "LATEST"}, rows)
+}
+
+func listReleases(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+
+    releases, err := e.client.ListPackageRelease(args[0])
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(releases))
+    for _, r := range releases {
+        rows = append(rows, []string{r.Name, r.Digest, formatTime(r.CreatedAt)})
+    }
+    return e.out.print(releases, []string{"NAME", "DIGEST", "CREATED"}, rows)
+}
+
+func listBuildflows(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    flows, err := e.client.ListBuildflow()
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(flows))
+    for _, f := range flows {
+        if f.Project == nil {
+            continue
+        }
+        last := ""
+        if f.LatestBuild != nil {
+            last = fmt.Sprintf("#%d %s", f.LatestBuild.ID, f.LatestBuild.Status)
+        }
+        rows = append(rows, []string{f.Project.ID, f.Project.Name, f.Project.RepoName, last})
+    }
+    return e.out.print(flows, []string{"ID", "NAME", "REPO", "LAST BUILD"}, rows)
+}
+
+func listBuilds(e *env, args []string) error {
+    if err := needArgs(args, 1); err != nil {
+        return err
+    }
+
+    builds, err := e.client.ListBuild(args[0])
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(builds))
+    for _, b := range builds {
+        ref := b.Ref
+        if b.Tag != "" {
+            ref = b.Tag
+        }
+        rows = append(rows, []string{strconv.Itoa(b.ID), b.Status, ref, b.TriggerMethod, formatTime(b.CreatedAt), b.Duration().String()})
+    }
+    return e.out.print(builds, []string{"ID", "STATUS", "REF", "TRIGGER", "CREATED", "DURATION"}, rows)
+}
+
+func triggerBuild(e *env, args []string) error {
+    if err := needArgs(args, 2); err != nil {
+        return err
+    }
+
+    b, err := e.client.TriggerBuild(args[0], &dao.BuildOptions{Branch: args[1]})
+    if err != nil {
+        return err
+    }
+
+    return e.out.print(b, []string{"ID", "STATUS"}, [][]string{{strconv.Itoa(b.ID), b.Status}})
+}
+
+func cancelBuild(e *env, args []string) error {
+    if err := needArgs(args, 2); err != nil {
+        return err
+    }
+    id, err := strconv.Atoi(args[1])
+    if err != nil {
+        return &usageError{fmt.Sprintf("invalid build id %s", args[1])}
+    }
+
+    if err := e.client.CancelBuild(args[0], id); err != nil {
+        return err
+    }
+
+    e.out.message("build %d canceled", id)
+    return nil
+}
+
+func buildLog(e *env, args []string) error {
+    if err := needArgs(args, 2); err != nil {
+        return err
+    }
+    id, err := strconv.Atoi(args[1])
+    if err != nil {
+        return &usageError{fmt.Sprintf("invalid build id %s", args[1])}
+    }
+
+    if e.out.format == "table" {
+        return e.client.StreamBuildLog(args[0], id, dao.LogPrinter(os.Stdout))
+    }
+
+    log, err := e.client.GetBuildLog(args[0], id)
+    if err != nil {
+        return err
+    }
+    return e.out.print(log.Stages(), nil, nil)
+}
+
+func listNodes(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    clusters, err := e.client.ListCluster()
+    if err != nil {
+        return err
+    }
+
+    nodes := make([]*dao.Node, 0)
+    rows := make([][]string, 0)
+    for _, c := range clusters {
+        for _, n := range c.Nodes {
+            nodes = append(nodes, n)
+            rows = append(rows, []string{n.ID, n.Name, n.Hostname, c.Name, strconv.FormatBool(n.IsConnected), n.DockerStatus, strings.Join(n.Addrs, ",")})
+        }
+    }
+    return e.out.print(nodes, []string{"ID", "NAME", "HOSTNAME", "CLUSTER", "CONNECTED", "DOCKER", "ADDRS"}, rows)
+}
+
+func listClusters(e *env, args []string) error {
+    if err := needArgs(args, 0); err != nil {
+        return err
+    }
+
+    clusters, err := e.client.ListCluster()
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]string, 0, len(clusters))
+    for _, c := range clusters {
+        rows = append(rows, []string{c.ID, c.Name, strconv.FormatBool(c.IsDefault), strconv.Itoa(len(c.Nodes))})
+    }
+    return e.out.print(clusters, []string{"ID", "NAME", "DEFAULT", "NODES"}, rows)
+}
+
+func importCmd(e *env, args []string) error {
+    if len(args) > 1 {
+        return needArgs(args, 1)
+    }
+
+    cluster := e.defaults["cluster"]
+    if len(args) == 1 {
+        cluster = args[0]
+    }
+
+    var cmd string
+    var err error
+    if cluster != "" {
+        cmd, err = e.client.GetImportCmdForCluster(cluster)
+    } else {
+        cmd, err = e.client.GetImportCmd()
+    }
+    if err != nil {
+        return err
+    }
+
+    v := map[string]string{"command": cmd}
+    return e.out.print(v, nil, [][]string{{cmd}})
+}
+
+func formatTime(unix int64) string {
+    if unix == 0 {
+        return ""
+    }
+    return time.Unix(unix, 0).Format("2006-01-02 15:04:05")
+}
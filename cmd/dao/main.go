@@ -0,0 +1,266 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/daocloud-hwu/dao"
+)
+
+const (
+    exitOK = iota
+    exitError
+    exitUsage
+    exitAuth
+    exitNotFound
+    exitServer
+    exitClient
+)
+
+type env struct {
+    client   *dao.Client
+    out      *printer
+    config   *dao.Config
+    path     string
+    profile  string
+    defaults map[string]string
+}
+
+type command struct {
+    args  string
+    usage string
+    run   func(e *env, args []string) error
+}
+
+type usageError struct {
+    msg string
+}
+
+func (e *usageError) Error() string {
+    return e.msg
+}
+
+type notFoundError struct {
+    kind string
+    id   string
+}
+
+func (e *notFoundError) Error() string {
+    return fmt.Sprintf("%s %s not found", e.kind, e.id)
+}
+
+var statusPattern = regexp.MustCompile(`Status code is (\d+)`)
+
+func main() {
+    os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+    flags := flag.NewFlagSet("dao", flag.ContinueOnError)
+    profile := flags.String("profile", "", "config profile, defaults to $DAO_PROFILE or the current profile")
+    host := flags.String("host", "", "API host, overrides the profile and $DAO_HOST")
+    token := flags.String("token", "", "access token, overrides the profile and $DAO_TOKEN")
+    internalHost := flags.String("internal-host", "", "internal API host, used by login")
+    internalToken := flags.String("internal-token", "", "internal API token, used by login")
+    format := flags.String("o", "", "output format: table, json or yaml, defaults to the profile output or table")
+    flags.Usage = func() { usage(flags) }
+
+    rest, err := parseFlags(flags, args)
+    if err != nil {
+        return exitUsage
+    }
+    if len(rest) == 0 {
+        usage(flags)
+        return exitUsage
+    }
+
+    group, ok := groups[rest[0]]
+    if !ok {
+        fmt.Fprintf(os.Stderr, "unknown command %s\n", rest[0])
+        usage(flags)
+        return exitUsage
+    }
+
+    name, cmdArgs := "", rest[1:]
+    if _, single := group[""]; !single {
+        if len(cmdArgs) == 0 {
+            groupUsage(rest[0], group)
+            return exitUsage
+        }
+        name, cmdArgs = cmdArgs[0], cmdArgs[1:]
+    }
+
+    cmd, ok := group[name]
+    if !ok {
+        fmt.Fprintf(os.Stderr, "unknown command %s %s\n", rest[0], name)
+        groupUsage(rest[0], group)
+        return exitUsage
+    }
+
+    e, err := newEnv(*profile, *format, rest[0] == "login")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %s\n", err)
+        return exitError
+    }
+    if f := e.out.format; f != "table" && f != "json" && f != "yaml" {
+        fmt.Fprintf(os.Stderr, "unknown output format %s\n", f)
+        return exitUsage
+    }
+    overrides := []struct {
+        value string
+        field *string
+    }{
+        {*host, &e.client.Host},
+        {*token, &e.client.AuthToken},
+        {*internalHost, &e.client.InternalHost},
+        {*internalToken, &e.client.InternalToken},
+    }
+    for _, o := range overrides {
+        if o.value != "" {
+            *o.field = o.value
+        }
+    }
+
+    if err := cmd.run(e, cmdArgs); err != nil {
+        fmt.Fprintf(os.Stderr, "error: %s\n", err)
+        if _, ok := err.(*usageError); ok {
+            fmt.Fprintf(os.Stderr, "usage: dao %s %s %s\n", rest[0], name, cmd.args)
+        }
+        return exitCode(err)
+    }
+
+    return exitOK
+}
+
+// parseFlags parses the global flags wherever they appear, so they may
+// follow the command as in "dao apps list -o json". Arguments after "--" are
+// never parsed as flags.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+    positional := make([]string, 0, len(args))
+    for {
+        if err := flags.Parse(args); err != nil {
+            return nil, err
+        }
+
+        rest := flags.Args()
+        if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+            return append(positional, rest...), nil
+        }
+        if len(rest) == 0 {
+            return positional, nil
+        }
+        positional = append(positional, rest[0])
+        args = rest[1:]
+    }
+}
+
+// newEnv loads the client from the profile. A missing profile is only
+// accepted for login, which creates it. An empty format falls back to the
+// profile default output.
+func newEnv(profile, format string, create bool) (*env, error) {
+    path, err := dao.DefaultConfigPath()
+    if err != nil {
+        return nil, err
+    }
+
+    cfg, err := dao.LoadConfig(path)
+    if err != nil {
+        return nil, err
+    }
+
+    p := cfg.Profile(profile)
+    if p == nil {
+        if profile != "" && !create {
+            return nil, fmt.Errorf("profile %s not found in %s", profile, path)
+        }
+        p = new(dao.Profile)
+    }
+    client := p.Client()
+    dao.ApplyEnv(client)
+
+    if format == "" {
+        format = p.Defaults["output"]
+    }
+    if format == "" {
+        format = "table"
+    }
+
+    return &env{
+        client:   client,
+        out:      &printer{format: format, w: os.Stdout},
+        config:   cfg,
+        path:     path,
+        profile:  cfg.ProfileName(profile),
+        defaults: p.Defaults,
+    }, nil
+}
+
+// exitCode maps the status code found in a client error to an exit code.
+func exitCode(err error) int {
+    switch err.(type) {
+    case *usageError:
+        return exitUsage
+    case *notFoundError:
+        return exitNotFound
+    }
+
+    m := statusPattern.FindStringSubmatch(err.Error())
+    if m == nil {
+        return exitError
+    }
+
+    status, _ := strconv.Atoi(m[1])
+    switch {
+    case status == 401 || status == 403:
+        return exitAuth
+    case status == 404:
+        return exitNotFound
+    case status/100 == 5:
+        return exitServer
+    case status/100 == 4:
+        return exitClient
+    }
+    return exitError
+}
+
+func needArgs(args []string, n int) error {
+    if len(args) != n {
+        return &usageError{fmt.Sprintf("expected %d arguments, got %d", n, len(args))}
+    }
+    return nil
+}
+
+func usage(flags *flag.FlagSet) {
+    fmt.Fprintf(os.Stderr, "usage: dao <command> [args] [flags]\n\ncommands:\n")
+    names := make([]string, 0, len(groups))
+    for name := range groups {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    for _, name := range names {
+        fmt.Fprintf(os.Stderr, "  %s\n", name)
+    }
+    fmt.Fprintf(os.Stderr, "\nflags:\n")
+    flags.PrintDefaults()
+    fmt.Fprintf(os.Stderr, "\nexit codes: 1 error, 2 usage, 3 unauthorized, 4 not found, 5 server error, 6 rejected request\n")
+}
+
+func groupUsage(group string, cmds map[string]*command) {
+    names := make([]string, 0, len(cmds))
+    for name := range cmds {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    fmt.Fprintf(os.Stderr, "usage:\n")
+    for _, name := range names {
+        cmd := cmds[name]
+        line := strings.TrimSpace(fmt.Sprintf("dao %s %s %s", group, name, cmd.args))
+        fmt.Fprintf(os.Stderr, "  %-50s %s\n", line, cmd.usage)
+    }
+}
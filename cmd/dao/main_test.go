@@ -0,0 +1,58 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "reflect"
+    "testing"
+)
+
+func TestParseFlags(t *testing.T) {
+    tests := []struct {
+        args   []string
+        want   []string
+        format string
+    }{
+        {[]string{"apps", "list"}, []string{"apps", "list"}, "table"},
+        {[]string{"-o", "json", "apps", "list"}, []string{"apps", "list"}, "json"},
+        {[]string{"apps", "list", "-o", "json"}, []string{"apps", "list"}, "json"},
+        {[]string{"apps", "-o=yaml", "get", "a1"}, []string{"apps", "get", "a1"}, "yaml"},
+        {[]string{"apps", "get", "--", "-o", "json"}, []string{"apps", "get", "-o", "json"}, "table"},
+    }
+
+    for _, tt := range tests {
+        flags := flag.NewFlagSet("dao", flag.ContinueOnError)
+        format := flags.String("o", "table", "")
+
+        got, err := parseFlags(flags, tt.args)
+        if err != nil {
+            t.Errorf("parseFlags(%v) error = %v", tt.args, err)
+            continue
+        }
+        if !reflect.DeepEqual(got, tt.want) || *format != tt.format {
+            t.Errorf("parseFlags(%v) = %v with -o %s, want %v with -o %s", tt.args, got, *format, tt.want, tt.format)
+        }
+    }
+}
+
+func TestExitCode(t *testing.T) {
+    tests := []struct {
+        err  error
+        want int
+    }{
+        {&usageError{"expected 1 arguments, got 0"}, exitUsage},
+        {&notFoundError{"app", "a1"}, exitNotFound},
+        {errors.New("Status code is 401"), exitAuth},
+        {errors.New("Status code is 403, reason forbidden"), exitAuth},
+        {errors.New("Status code is 404"), exitNotFound},
+        {errors.New("Status code is 409, reason conflict"), exitClient},
+        {errors.New("Status code is 502"), exitServer},
+        {errors.New("dial tcp: connection refused"), exitError},
+    }
+
+    for _, tt := range tests {
+        if got := exitCode(tt.err); got != tt.want {
+            t.Errorf("exitCode(%q) = %d, want %d", tt.err, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "text/tabwriter"
+)
+
+type printer struct {
+    format string
+    w      io.Writer
+}
+
+// print writes v as JSON or YAML, or the given rows as a table.
+func (p *printer) print(v interface{}, headers []string, rows [][]string) error {
+    switch p.format {
+    case "json":
+        out, err := json.MarshalIndent(v, "", "  ")
+        if err != nil {
+            return err
+        }
+        _, err = fmt.Fprintf(p.w, "%s\n", out)
+        return err
+    case "yaml":
+        return writeYAML(p.w, v)
+    default:
+        tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+        if len(headers) > 0 {
+            fmt.Fprintln(tw, strings.Join(headers, "\t"))
+        }
+        for _, row := range rows {
+            fmt.Fprintln(tw, strings.Join(row, "\t"))
+        }
+        return tw.Flush()
+    }
+}
+
+func (p *printer) message(format string, args ...interface{}) {
+    if p.format == "table" {
+        fmt.Fprintf(p.w, format + "\n", args...)
+    } else {
+        fmt.Fprintf(os.Stderr, format + "\n", args...)
+    }
+}
+
+// writeYAML encodes v through its JSON form, so field names follow the json
+// tags of the library types.
+func writeYAML(w io.Writer, v interface{}) error {
+    raw, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+
+    dec := json.NewDecoder(bytes.NewReader(raw))
+    dec.UseNumber()
+    var doc interface{}
+    if err := dec.Decode(&doc); err != nil {
+        return err
+    }
+
+    buf := new(bytes.Buffer)
+    yamlValue(buf, doc, 0, false)
+    _, err = w.Write(buf.Bytes())
+    return err
+}
+
+func yamlValue(buf *bytes.Buffer, v interface{}, indent int, inList bool) {
+    pad := strings.Repeat("  ", indent)
+
+    switch val := v.(type) {
+    case map[string]interface{}:
+        if len(val) == 0 {
+            buf.WriteString("{}\n")
+            return
+        }
+        keys := make([]string, 0, len(val))
+        for k := range val {
+            keys = append(keys, k)
+        }
+        sort.Strings(keys)
+
+        for i, k := range keys {
+            if i > 0 || !inList {
+                buf.WriteString(pad)
+            }
+            buf.WriteString(yamlScalar(k) + ":")
+            yamlChild(buf, val[k], indent)
+        }
+    case []interface{}:
+        if len(val) == 0 {
+            buf.WriteString("[]\n")
+            return
+        }
+        for i, item := range val {
+            if i > 0 || !inList {
+                buf.WriteString(pad)
+            }
+            buf.WriteString("- ")
+            if isYAMLScalar(item) {
+                buf.WriteString(yamlScalar(item) + "\n")
+            } else {
+                yamlValue(buf, item, indent + 1, true)
+            }
+        }
+    default:
+        buf.WriteString(yamlScalar(val) + "\n")
+    }
+}
+
+func yamlChild(buf *bytes.Buffer, v interface{}, indent int) {
+    switch val := v.(type) {
+    case map[string]interface{}:
+        if len(val) == 0 {
+            buf.WriteString(" {}\n")
+            return
+        }
+        buf.WriteString("\n")
+        yamlValue(buf, val, indent + 1, false)
+    case []interface{}:
+        if len(val) == 0 {
+            buf.WriteString(" []\n")
+            return
+        }
+        buf.WriteString("\n")
+        yamlValue(buf, val, indent + 1, false)
+    default:
+        buf.WriteString(" " + yamlScalar(val) + "\n")
+    }
+}
+
+func isYAMLScalar(v interface{}) bool {
+    switch val := v.(type) {
+    case map[string]interface{}:
+        return len(val) == 0
+    case []interface{}:
+        return len(val) == 0
+    }
+    return true
+}
+
+func yamlScalar(v interface{}) string {
+    switch val := v.(type) {
+    case nil:
+        return "null"
+    case bool:
+        return strconv.FormatBool(val)
+    case json.Number:
+        return val.String()
+    case map[string]interface{}:
+        return "{}"
+    case []interface{}:
+        return "[]"
+    case string:
+        if yamlNeedsQuote(val) {
+            return strconv.Quote(val)
+        }
+        return val
+    }
+    return fmt.Sprint(v)
+}
+
+func yamlNeedsQuote(s string) bool {
+    if s == "" || strings.TrimSpace(s) != s {
+        return true
+    }
+    switch strings.ToLower(s) {
+    case "null", "~", "true", "false", "yes", "no", "on", "off":
+        return true
+    }
+    if _, err := strconv.ParseFloat(s, 64); err == nil {
+        return true
+    }
+    if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+        return true
+    }
+    return strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.ContainsAny(s, "\n\t\\")
+}
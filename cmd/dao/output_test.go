@@ -0,0 +1,51 @@
+package main
+
+import (
+    "bytes"
+    "testing"
+)
+
+func TestWriteYAML(t *testing.T) {
+    tests := []struct {
+        v    interface{}
+        want string
+    }{
+        {
+            map[string]interface{}{
+                "name":  "web",
+                "port":  80,
+                "tags":  []string{"a", "b"},
+                "meta":  map[string]string{"x": "1"},
+                "empty": []int{},
+                "none":  nil,
+                "obj":   map[string]int{},
+            },
+            "empty: []\nmeta:\n  x: \"1\"\nname: web\nnone: null\nobj: {}\nport: 80\ntags:\n  - a\n  - b\n",
+        },
+        {
+            []map[string]interface{}{{"a": 1, "b": "x"}, {"a": 2, "c": []string{"y"}}},
+            "- a: 1\n  b: x\n- a: 2\n  c:\n    - y\n",
+        },
+        {
+            [][]int{{1, 2}, {}},
+            "- - 1\n  - 2\n- []\n",
+        },
+        {
+            []string{"", "true", "1.5", "- dash", "a: b", "multi\nline", " pad", "ok value"},
+            "- \"\"\n- \"true\"\n- \"1.5\"\n- \"- dash\"\n- \"a: b\"\n- \"multi\\nline\"\n- \" pad\"\n- ok value\n",
+        },
+        {"plain", "plain\n"},
+        {map[string]string{}, "{}\n"},
+    }
+
+    for _, tt := range tests {
+        buf := new(bytes.Buffer)
+        if err := writeYAML(buf, tt.v); err != nil {
+            t.Errorf("writeYAML(%v) error = %v", tt.v, err)
+            continue
+        }
+        if got := buf.String(); got != tt.want {
+            t.Errorf("writeYAML(%v) =\n%s\nwant\n%s", tt.v, got, tt.want)
+        }
+    }
+}
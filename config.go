@@ -0,0 +1,214 @@
+package dao
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+)
+
+const defaultProfile = "default"
+
+// Profile holds the connection settings of one account. Defaults holds the
+// defaults of the dao command: "output" is the output format and "cluster"
+// the cluster whose import command is printed.
+type Profile struct {
+    Host          string            `json:"host"`
+    InternalHost  string            `json:"internal_host,omitempty"`
+    InternalToken string            `json:"internal_token,omitempty"`
+    RegistryHost  string            `json:"registry_host,omitempty"`
+    Token         string            `json:"token,omitempty"`
+    ClientID      string            `json:"client_id,omitempty"`
+    ClientSecret  string            `json:"client_secret,omitempty"`
+    TokenURL      string            `json:"token_url,omitempty"`
+    Defaults      map[string]string `json:"defaults,omitempty"`
+}
+
+type Config struct {
+    Current  string              `json:"current"`
+    Profiles map[string]*Profile `json:"profiles"`
+}
+
+// DefaultConfigPath returns $DAO_CONFIG, or ~/.dao/config.
+func DefaultConfigPath() (string, error) {
+    if path := os.Getenv("DAO_CONFIG"); path != "" {
+        return path, nil
+    }
+
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".dao", "config"), nil
+}
+
+// LoadConfig reads the config file, returning an empty config when the file
+// does not exist yet.
+func LoadConfig(path string) (*Config, error) {
+    cfg := &Config{Profiles: make(map[string]*Profile)}
+
+    data, err := ioutil.ReadFile(path)
+    if os.IsNotExist(err) {
+        return cfg, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := json.Unmarshal(data, cfg); err != nil {
+        return nil, fmt.Errorf("parse %s: %s", path, err)
+    }
+    if cfg.Profiles == nil {
+        cfg.Profiles = make(map[string]*Profile)
+    }
+
+    return cfg, nil
+}
+
+// Save writes the config readable by the owner only, since profiles hold
+// access tokens.
+func (cfg *Config) Save(path string) error {
+    data, err := json.MarshalIndent(cfg, "", "  ")
+    if err != nil {
+        return err
+    }
+
+    dir := filepath.Dir(path)
+    if err := os.MkdirAll(dir, 0700); err != nil {
+        return err
+    }
+
+    tmp, err := ioutil.TempFile(dir, ".config-")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(0600); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+
+    return os.Rename(tmp.Name(), path)
+}
+
+// ProfileName resolves the profile to use: name, then $DAO_PROFILE, then the
+// current profile of the config, then "default".
+func (cfg *Config) ProfileName(name string) string {
+    if name == "" {
+        name = os.Getenv("DAO_PROFILE")
+    }
+    if name == "" {
+        name = cfg.Current
+    }
+    if name == "" {
+        name = defaultProfile
+    }
+    return name
+}
+
+func (cfg *Config) Profile(name string) *Profile {
+    return cfg.Profiles[cfg.ProfileName(name)]
+}
+
+func (cfg *Config) SetProfile(name string, p *Profile) {
+    cfg.Profiles[cfg.ProfileName(name)] = p
+}
+
+// Redacted returns a copy of the profile safe to display, with secrets
+// masked. Fields are copied one by one, so a field added later stays hidden
+// until it is listed here.
+func (p *Profile) Redacted() *Profile {
+    mask := func(secret string) string {
+        if secret == "" {
+            return ""
+        }
+        return "***"
+    }
+
+    r := &Profile{
+        Host:         p.Host,
+        InternalHost: p.InternalHost,
+        RegistryHost: p.RegistryHost,
+        ClientID:     p.ClientID,
+        TokenURL:     p.TokenURL,
+        Defaults:     p.Defaults,
+    }
+    r.Token = mask(p.Token)
+    r.InternalToken = mask(p.InternalToken)
+    r.ClientSecret = mask(p.ClientSecret)
+    return r
+}
+
+// Client returns a client for the profile. A profile with a ClientID
+// authenticates with the OAuth2 client credentials flow.
+func (p *Profile) Client() *Client {
+    c := &Client{
+        Host:          p.Host,
+        AuthToken:     p.Token,
+        InternalHost:  p.InternalHost,
+        InternalToken: p.InternalToken,
+        RegistryHost:  p.RegistryHost,
+    }
+
+    if p.ClientID != "" {
+        c.Credentials = &ClientCredentials{
+            TokenURL:     p.TokenURL,
+            ClientID:     p.ClientID,
+            ClientSecret: p.ClientSecret,
+        }
+    }
+    return c
+}
+
+// NewClientFromProfile builds a client from a profile of the default config
+// file. DAO_HOST, DAO_TOKEN, DAO_INTERNAL_HOST, DAO_INTERNAL_TOKEN and
+// DAO_REGISTRY_HOST override the profile values. An unknown profile is an
+// error unless name is empty, in which case only the env vars are used.
+func NewClientFromProfile(name string) (*Client, error) {
+    path, err := DefaultConfigPath()
+    if err != nil {
+        return nil, err
+    }
+
+    cfg, err := LoadConfig(path)
+    if err != nil {
+        return nil, err
+    }
+
+    p := cfg.Profile(name)
+    if p == nil {
+        if name != "" {
+            return nil, fmt.Errorf("profile %s not found in %s", name, path)
+        }
+        p = new(Profile)
+    }
+
+    c := p.Client()
+    ApplyEnv(c)
+    return c, nil
+}
+
+// ApplyEnv overrides the client hosts and tokens with the DAO_* env vars
+// that are set.
+func ApplyEnv(c *Client) {
+    for env, field := range map[string]*string{
+        "DAO_HOST":           &c.Host,
+        "DAO_TOKEN":          &c.AuthToken,
+        "DAO_INTERNAL_HOST":  &c.InternalHost,
+        "DAO_INTERNAL_TOKEN": &c.InternalToken,
+        "DAO_REGISTRY_HOST":  &c.RegistryHost,
+    } {
+        if v := os.Getenv(env); v != "" {
+            *field = v
+        }
+    }
+}
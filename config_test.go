@@ -0,0 +1,41 @@
+package dao
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestProfileRedacted(t *testing.T) {
+    public := map[string]bool{"Host": true, "InternalHost": true, "RegistryHost": true, "ClientID": true, "TokenURL": true}
+
+    p := new(Profile)
+    v := reflect.ValueOf(p).Elem()
+    for i := 0; i < v.NumField(); i++ {
+        if v.Field(i).Kind() == reflect.String {
+            v.Field(i).SetString("s3cret")
+        }
+    }
+    p.Defaults = map[string]string{"output": "json"}
+
+    r := reflect.ValueOf(p.Redacted()).Elem()
+    for i := 0; i < r.NumField(); i++ {
+        name, f := r.Type().Field(i).Name, r.Field(i)
+        if f.Kind() != reflect.String {
+            continue
+        }
+        if public[name] {
+            if f.String() != "s3cret" {
+                t.Errorf("Redacted().%s = %q, want it kept", name, f.String())
+            }
+        } else if f.String() == "s3cret" {
+            t.Errorf("Redacted().%s is not masked", name)
+        }
+    }
+    if r.FieldByName("Defaults").Len() != 1 {
+        t.Errorf("Redacted() dropped the defaults")
+    }
+
+    if got := (&Profile{Host: "h"}).Redacted(); got.Token != "" || got.ClientSecret != "" {
+        t.Errorf("Redacted() masked empty secrets: %+v", got)
+    }
+}
@@ -0,0 +1,135 @@
+package dao
+
+import (
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+type Connection struct {
+    Kind     string `json:"kind"`
+    Host     string `json:"host"`
+    Port     int    `json:"port"`
+    User     string `json:"user"`
+    Password string `json:"password"`
+    Database string `json:"database"`
+    URL      string `json:"url"`
+}
+
+var connectionKinds = []struct {
+    kind   string
+    scheme string
+    port   int
+}{
+    {"mysql", "mysql", 3306},
+    {"postgresql", "postgres", 5432},
+    {"mongodb", "mongodb", 27017},
+    {"redis", "redis", 6379},
+}
+
+func (s *ServiceInstance) EnvMap() map[string]string {
+    env := make(map[string]string)
+    for _, e := range s.EnvVars {
+        env[e.Name] = e.Value
+    }
+    return env
+}
+
+func (s *ServiceInstance) envNames() []string {
+    names := make([]string, 0, len(s.EnvVars))
+    for _, e := range s.EnvVars {
+        names = append(names, e.Name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// WriteEnvFile writes the instance env vars in .env format, sorted by name.
+func (s *ServiceInstance) WriteEnvFile(w io.Writer) error {
+    env := s.EnvMap()
+    for _, name := range s.envNames() {
+        if _, err := fmt.Fprintf(w, "%s=%s\n", name, quoteEnvValue(env[name])); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *ServiceInstance) EnvJSON() ([]byte, error) {
+    return json.MarshalIndent(s.EnvMap(), "", "  ")
+}
+
+// SecretYAML renders the instance env vars as a Kubernetes Secret manifest.
+func (s *ServiceInstance) SecretYAML(name string) string {
+    if name == "" {
+        name = s.Name
+    }
+
+    env := s.EnvMap()
+    b := new(strings.Builder)
+    fmt.Fprintf(b, "apiVersion: v1\n")
+    fmt.Fprintf(b, "kind: Secret\n")
+    fmt.Fprintf(b, "metadata:\n")
+    fmt.Fprintf(b, "  name: %s\n", strconv.Quote(name))
+    fmt.Fprintf(b, "type: Opaque\n")
+    fmt.Fprintf(b, "data:\n")
+    for _, k := range s.envNames() {
+        fmt.Fprintf(b, "  %s: %s\n", k, base64.StdEncoding.EncodeToString([]byte(env[k])))
+    }
+    return b.String()
+}
+
+// Connection builds a connection descriptor for MySQL, PostgreSQL, MongoDB
+// and Redis instances from the <PREFIX>_PORT_<port>_TCP_ADDR style env vars
+// the platform injects.
+func (s *ServiceInstance) Connection() (*Connection, error) {
+    env := s.EnvMap()
+
+    for _, k := range connectionKinds {
+        suffix := fmt.Sprintf("_PORT_%d_TCP_ADDR", k.port)
+        for _, name := range s.envNames() {
+            if !strings.HasSuffix(name, suffix) {
+                continue
+            }
+            prefix := strings.TrimSuffix(name, suffix)
+
+            conn := new(Connection)
+            conn.Kind = k.kind
+            conn.Host = env[name]
+            conn.Port = k.port
+            if p, err := strconv.Atoi(env[fmt.Sprintf("%s_PORT_%d_TCP_PORT", prefix, k.port)]); err == nil {
+                conn.Port = p
+            }
+            conn.User = env[prefix + "_USERNAME"]
+            conn.Password = env[prefix + "_PASSWORD"]
+            conn.Database = env[prefix + "_INSTANCE_NAME"]
+
+            u := &url.URL{Scheme: k.scheme, Host: fmt.Sprintf("%s:%d", conn.Host, conn.Port)}
+            if conn.User != "" || conn.Password != "" {
+                u.User = url.UserPassword(conn.User, conn.Password)
+            }
+            if conn.Database != "" && k.kind != "redis" {
+                u.Path = "/" + conn.Database
+            }
+            conn.URL = u.String()
+
+            return conn, nil
+        }
+    }
+
+    return nil, fmt.Errorf("service instance %s is not a known database service", s.Name)
+}
+
+func quoteEnvValue(v string) string {
+    if v != "" && !strings.ContainsAny(v, " \t\n\r\"'\\$#=") {
+        return v
+    }
+
+    r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`)
+    return `"` + r.Replace(v) + `"`
+}
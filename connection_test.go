@@ -0,0 +1,29 @@
+package dao
+
+import (
+    "testing"
+)
+
+func TestQuoteEnvValue(t *testing.T) {
+    tests := []struct {
+        value string
+        want  string
+    }{
+        {"plain", "plain"},
+        {"mysql://u:p@10.0.0.1:3306/db", "mysql://u:p@10.0.0.1:3306/db"},
+        {"", `""`},
+        {"two words", `"two words"`},
+        {`say "hi"`, `"say \"hi\""`},
+        {`C:\temp`, `"C:\\temp"`},
+        {"$HOME", `"\$HOME"`},
+        {"line1\nline2", `"line1\nline2"`},
+        {"a=b", `"a=b"`},
+        {"it's", `"it's"`},
+    }
+
+    for _, tt := range tests {
+        if got := quoteEnvValue(tt.value); got != tt.want {
+            t.Errorf("quoteEnvValue(%q) = %s, want %s", tt.value, got, tt.want)
+        }
+    }
+}
@@ -0,0 +1,98 @@
+package dao
+
+type NodeHealth struct {
+    Node   *Node    `json:"node"`
+    Apps   []*App   `json:"apps"`
+    Stacks []*Stack `json:"stacks"`
+}
+
+type ClusterHealth struct {
+    ID           string         `json:"node_cluster_id"`
+    Name         string         `json:"node_cluster_name"`
+    Connected    int            `json:"connected"`
+    Disconnected int            `json:"disconnected"`
+    DockerStatus map[string]int `json:"docker_status"`
+    Nodes        []*NodeHealth  `json:"nodes"`
+}
+
+type HealthReport struct {
+    Clusters       []*ClusterHealth `json:"clusters"`
+    OrphanedApps   []*App           `json:"orphaned_apps"`
+    OrphanedStacks []*Stack         `json:"orphaned_stacks"`
+}
+
+// GetHealthReport summarizes every cluster and the apps and stacks placed on
+// its nodes. Apps and stacks whose node tags match no existing node are
+// reported as orphaned; those without tags are not placed on nodes and are
+// skipped.
+func (c *Client) GetHealthReport() (*HealthReport, error) {
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    apps, err := c.ListApp()
+    if err != nil {
+        return nil, err
+    }
+
+    stacks, err := c.ListStack()
+    if err != nil {
+        return nil, err
+    }
+
+    report := new(HealthReport)
+    report.Clusters = make([]*ClusterHealth, 0, len(cs))
+    report.OrphanedApps = make([]*App, 0)
+    report.OrphanedStacks = make([]*Stack, 0)
+
+    placedApps := make(map[string]bool)
+    placedStacks := make(map[string]bool)
+
+    for _, cluster := range cs {
+        h := new(ClusterHealth)
+        h.ID = cluster.ID
+        h.Name = cluster.Name
+        h.DockerStatus = make(map[string]int)
+        h.Nodes = make([]*NodeHealth, 0, len(cluster.Nodes))
+
+        for _, n := range cluster.Nodes {
+            if n.IsConnected {
+                h.Connected++
+            } else {
+                h.Disconnected++
+            }
+            h.DockerStatus[n.DockerStatus]++
+
+            nh := &NodeHealth{Node: n, Apps: make([]*App, 0), Stacks: make([]*Stack, 0)}
+            for _, app := range apps {
+                if tags := app.tags(); len(tags) > 0 && tagsMatchNode(tags, cluster.ID, n) {
+                    nh.Apps = append(nh.Apps, app)
+                    placedApps[app.ID] = true
+                }
+            }
+            for _, s := range stacks {
+                if tags := s.tags(); len(tags) > 0 && tagsMatchNode(tags, cluster.ID, n) {
+                    nh.Stacks = append(nh.Stacks, s)
+                    placedStacks[s.ID] = true
+                }
+            }
+            h.Nodes = append(h.Nodes, nh)
+        }
+
+        report.Clusters = append(report.Clusters, h)
+    }
+
+    for _, app := range apps {
+        if len(app.tags()) > 0 && !placedApps[app.ID] {
+            report.OrphanedApps = append(report.OrphanedApps, app)
+        }
+    }
+    for _, s := range stacks {
+        if len(s.tags()) > 0 && !placedStacks[s.ID] {
+            report.OrphanedStacks = append(report.OrphanedStacks, s)
+        }
+    }
+
+    return report, nil
+}
@@ -0,0 +1,243 @@
+package dao
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+type ImageConfig struct {
+    Architecture string           `json:"architecture"`
+    OS           string           `json:"os"`
+    Created      string           `json:"created"`
+    Config       *ContainerConfig `json:"config"`
+}
+
+type ContainerConfig struct {
+    User         string              `json:"User"`
+    Env          []string            `json:"Env"`
+    Entrypoint   []string            `json:"Entrypoint"`
+    Cmd          []string            `json:"Cmd"`
+    WorkingDir   string              `json:"WorkingDir"`
+    ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+    Labels       map[string]string   `json:"Labels"`
+}
+
+type ImageInfo struct {
+    Repository string        `json:"repository"`
+    Reference  string        `json:"reference"`
+    Digest     string        `json:"digest"`
+    Size       int64         `json:"size"`
+    Layers     []*Descriptor `json:"layers"`
+    Config     *ImageConfig  `json:"config"`
+}
+
+type PortDiff struct {
+    Image       []*PortInfo `json:"image"`
+    API         []*PortInfo `json:"api"`
+    OnlyInImage []*PortInfo `json:"only_in_image"`
+    OnlyInAPI   []*PortInfo `json:"only_in_api"`
+}
+
+func (r *Registry) GetManifest(repo, ref string) (*Manifest, string, error) {
+    res, err := r.request("GET", r.link(fmt.Sprintf("/v2/%s/manifests/%s", repo, ref)), nil, nil, pullScope(repo))
+    if err != nil {
+        return nil, "", err
+    }
+    defer res.Body.Close()
+
+    body, err := ioutil.ReadAll(res.Body)
+    if err != nil {
+        return nil, "", err
+    }
+    if res.StatusCode/100 != 2 {
+        return nil, "", fmt.Errorf("Status code is %d, reason %s", res.StatusCode, body)
+    }
+
+    m := new(Manifest)
+    if err := json.Unmarshal(body, m); err != nil {
+        return nil, "", err
+    }
+    if m.MediaType == "" {
+        m.MediaType = res.Header.Get("Content-Type")
+    }
+
+    digest := res.Header.Get("Docker-Content-Digest")
+    if digest == "" {
+        digest = sha256Digest(body)
+    }
+    return m, digest, nil
+}
+
+func (r *Registry) GetBlob(repo, digest string) ([]byte, error) {
+    res, err := r.request("GET", r.link(fmt.Sprintf("/v2/%s/blobs/%s", repo, digest)), nil, nil, pullScope(repo))
+    if err != nil {
+        return nil, err
+    }
+    defer res.Body.Close()
+
+    body, err := ioutil.ReadAll(res.Body)
+    if err != nil {
+        return nil, err
+    }
+    if res.StatusCode/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d, reason %s", res.StatusCode, body)
+    }
+    if sha256Digest(body) != digest {
+        return nil, fmt.Errorf("blob %s digest mismatch", digest)
+    }
+
+    return body, nil
+}
+
+func (r *Registry) GetImageConfig(repo, digest string) (*ImageConfig, error) {
+    body, err := r.GetBlob(repo, digest)
+    if err != nil {
+        return nil, err
+    }
+
+    config := new(ImageConfig)
+    if err := json.Unmarshal(body, config); err != nil {
+        return nil, err
+    }
+
+    return config, nil
+}
+
+// InspectImage reads the manifest and config of repo:ref. For multi-platform
+// images the linux/amd64 manifest is used.
+func (r *Registry) InspectImage(repo, ref string) (*ImageInfo, error) {
+    m, digest, err := r.GetManifest(repo, ref)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(m.Manifests) > 0 {
+        desc := m.Manifests[0]
+        for _, d := range m.Manifests {
+            if d.Platform != nil && d.Platform.OS == "linux" && d.Platform.Architecture == "amd64" {
+                desc = d
+                break
+            }
+        }
+
+        if m, digest, err = r.GetManifest(repo, desc.Digest); err != nil {
+            return nil, err
+        }
+    }
+    if m.Config == nil {
+        return nil, fmt.Errorf("manifest %s of %s has no config", digest, repo)
+    }
+
+    config, err := r.GetImageConfig(repo, m.Config.Digest)
+    if err != nil {
+        return nil, err
+    }
+
+    info := new(ImageInfo)
+    info.Repository = repo
+    info.Reference = ref
+    info.Digest = digest
+    info.Layers = m.Layers
+    info.Config = config
+    for _, l := range m.Layers {
+        info.Size += l.Size
+    }
+
+    return info, nil
+}
+
+func (r *Registry) InspectRelease(p *Package, release string) (*ImageInfo, error) {
+    return r.InspectImage(p.Repository(), release)
+}
+
+// ComparePorts compares the ports exposed by the release image with the ones
+// reported by GetPortInfo.
+func (r *Registry) ComparePorts(p *Package, release string) (*PortDiff, error) {
+    info, err := r.InspectRelease(p, release)
+    if err != nil {
+        return nil, err
+    }
+
+    ports, err := r.client.GetPortInfo(p.ID, release)
+    if err != nil {
+        return nil, err
+    }
+
+    diff := new(PortDiff)
+    diff.Image = info.Ports()
+    diff.API = ports
+    diff.OnlyInImage = portsMissing(diff.Image, diff.API)
+    diff.OnlyInAPI = portsMissing(diff.API, diff.Image)
+
+    return diff, nil
+}
+
+func (i *ImageInfo) Ports() []*PortInfo {
+    ports := make([]*PortInfo, 0)
+    if i.Config == nil || i.Config.Config == nil {
+        return ports
+    }
+
+    for p := range i.Config.Config.ExposedPorts {
+        proto := "tcp"
+        if slash := strings.Index(p, "/"); slash >= 0 {
+            proto = p[slash+1:]
+            p = p[:slash]
+        }
+        port, err := strconv.Atoi(p)
+        if err != nil {
+            continue
+        }
+        ports = append(ports, &PortInfo{Protocol: proto, Port: port})
+    }
+
+    sort.Slice(ports, func(a, b int) bool {
+        if ports[a].Port != ports[b].Port {
+            return ports[a].Port < ports[b].Port
+        }
+        return ports[a].Protocol < ports[b].Protocol
+    })
+    return ports
+}
+
+func (i *ImageInfo) Env() map[string]string {
+    env := make(map[string]string)
+    if i.Config == nil || i.Config.Config == nil {
+        return env
+    }
+
+    for _, e := range i.Config.Config.Env {
+        kv := strings.SplitN(e, "=", 2)
+        if len(kv) == 2 {
+            env[kv[0]] = kv[1]
+        } else {
+            env[kv[0]] = ""
+        }
+    }
+    return env
+}
+
+func portsMissing(from, in []*PortInfo) []*PortInfo {
+    missing := make([]*PortInfo, 0)
+    for _, a := range from {
+        found := false
+        for _, b := range in {
+            if a.Port == b.Port && strings.ToLower(a.Protocol) == strings.ToLower(b.Protocol) {
+                found = true
+                break
+            }
+        }
+        if !found {
+            missing = append(missing, a)
+        }
+    }
+    return missing
+}
+
+func pullScope(repo string) string {
+    return fmt.Sprintf("repository:%s:pull", repo)
+}
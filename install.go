@@ -0,0 +1,135 @@
+package dao
+
+import (
+    "bufio"
+    "fmt"
+    "strings"
+)
+
+type InstallOptions struct {
+    Proxy      string
+    Mirror     string
+    Sudo       bool
+    Offline    bool
+    ScriptPath string
+}
+
+type InstallPlan struct {
+    DownloadURL string   `json:"download_url"`
+    Token       string   `json:"token"`
+    KeeperURL   string   `json:"keeper_url"`
+    Commands    []string `json:"commands"`
+}
+
+func (p *InstallPlan) Command() string {
+    return strings.Join(p.Commands, " && ")
+}
+
+// GetInstallPlan returns the steps to import a host into the cluster, or into
+// the default cluster when clusterID is empty.
+func (c *Client) GetInstallPlan(clusterID string, opts *InstallOptions) (*InstallPlan, error) {
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, cluster := range cs {
+        if (clusterID == "" && cluster.IsDefault) || (clusterID != "" && cluster.ID == clusterID) {
+            return c.installPlan(cluster, opts)
+        }
+    }
+
+    if clusterID == "" {
+        return nil, fmt.Errorf("no available cluster")
+    }
+    return nil, fmt.Errorf("cluster %s not found", clusterID)
+}
+
+func (c *Client) installPlan(cluster *Cluster, opts *InstallOptions) (*InstallPlan, error) {
+    if opts == nil {
+        opts = new(InstallOptions)
+    }
+
+    env, err := c.GetSrEnv()
+    if err != nil {
+        return nil, err
+    }
+
+    base := env.DaoGetUrl
+    if opts.Mirror != "" {
+        base = strings.TrimRight(opts.Mirror, "/")
+    }
+
+    plan := new(InstallPlan)
+    plan.DownloadURL = fmt.Sprintf("%s/daomonit/install.sh", base)
+    plan.Token = cluster.Token
+    plan.KeeperURL = env.DaoKeeperUrl
+    plan.Commands = make([]string, 0)
+
+    sh := "sh"
+    if opts.Sudo {
+        sh = "sudo sh"
+        if opts.Proxy != "" {
+            sh = "sudo -E sh"
+        }
+    }
+
+    if opts.Proxy != "" {
+        plan.Commands = append(plan.Commands, fmt.Sprintf("export http_proxy=%s https_proxy=%s", opts.Proxy, opts.Proxy))
+    }
+
+    if opts.Offline {
+        script := opts.ScriptPath
+        if script == "" {
+            script = "install.sh"
+        }
+        plan.Commands = append(plan.Commands, fmt.Sprintf("%s %s %s %s", sh, script, plan.Token, plan.KeeperURL))
+    } else {
+        plan.Commands = append(plan.Commands, fmt.Sprintf("curl -sSL %s | %s -s %s %s", plan.DownloadURL, sh, plan.Token, plan.KeeperURL))
+    }
+
+    return plan, nil
+}
+
+// DetectOS returns the distribution id found in the content of /etc/os-release.
+func DetectOS(osRelease string) string {
+    id, like := "", ""
+    scanner := bufio.NewScanner(strings.NewReader(osRelease))
+    for scanner.Scan() {
+        kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+        if len(kv) != 2 {
+            continue
+        }
+        v := strings.Trim(kv[1], `"'`)
+        switch kv[0] {
+        case "ID":
+            id = v
+        case "ID_LIKE":
+            like = v
+        }
+    }
+
+    if osFamily(id) == "" && like != "" {
+        for _, l := range strings.Fields(like) {
+            if osFamily(l) != "" {
+                return l
+            }
+        }
+    }
+    return id
+}
+
+func osFamily(os string) string {
+    switch strings.ToLower(strings.TrimSpace(os)) {
+    case "ubuntu", "debian":
+        return "deb"
+    case "centos", "fedora", "rhel", "redhat", "rocky", "alma", "almalinux", "amzn", "amazon", "amazon linux":
+        return "rpm"
+    case "opensuse", "opensuse-leap", "opensuse-tumbleweed", "sles", "suse":
+        return "suse"
+    case "alpine":
+        return "apk"
+    default:
+        return ""
+    }
+}
@@ -0,0 +1,27 @@
+package dao
+
+import (
+    "testing"
+)
+
+func TestDetectOS(t *testing.T) {
+    tests := []struct {
+        osRelease string
+        want      string
+    }{
+        {"NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nID=ubuntu\nID_LIKE=debian\n", "ubuntu"},
+        {"ID=\"centos\"\nID_LIKE=\"rhel fedora\"\n", "centos"},
+        {"ID='alpine'\n", "alpine"},
+        {"ID=linuxmint\nID_LIKE=\"ubuntu debian\"\n", "ubuntu"},
+        {"ID=pop\nID_LIKE=\"foo debian\"\n", "debian"},
+        {"ID=arch\n", "arch"},
+        {"  ID=fedora  \n# comment\nbroken line\n", "fedora"},
+        {"", ""},
+    }
+
+    for _, tt := range tests {
+        if got := DetectOS(tt.osRelease); got != tt.want {
+            t.Errorf("DetectOS(%q) = %q, want %q", tt.osRelease, got, tt.want)
+        }
+    }
+}
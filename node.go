@@ -0,0 +1,150 @@
+package dao
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+)
+
+func (c *Client) GetNode(id string) (*Node, error) {
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, cluster := range cs {
+        for _, n := range cluster.Nodes {
+            if n.ID == id {
+                return n, nil
+            }
+        }
+    }
+
+    return nil, nil
+}
+
+func (c *Client) GetNodeByHostname(hostname string) (*Node, error) {
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    for _, cluster := range cs {
+        for _, n := range cluster.Nodes {
+            if n.Hostname == hostname {
+                return n, nil
+            }
+        }
+    }
+
+    return nil, nil
+}
+
+func (c *Client) RenameNode(id, name string) error {
+    return c.patchNode(id, map[string]interface{}{"node_name": name})
+}
+
+func (c *Client) CordonNode(id string) error {
+    return c.patchNode(id, map[string]interface{}{"schedulable": false})
+}
+
+func (c *Client) UncordonNode(id string) error {
+    return c.patchNode(id, map[string]interface{}{"schedulable": true})
+}
+
+func (c *Client) patchNode(id string, fields map[string]interface{}) error {
+    inbody, err := json.Marshal(fields)
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("PATCH", "/v1/single_runtime/nodes/" + id, nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+func (c *Client) LabelNode(id string, labels map[string]string) error {
+    data := struct {
+        Labels map[string]string `json:"labels"`
+    } {labels}
+
+    inbody, err := json.Marshal(data)
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("PATCH", fmt.Sprintf("/v1/single_runtime/nodes/%s/labels", id), nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+func (c *Client) UnlabelNode(id string, keys ...string) error {
+    data := struct {
+        Keys []string `json:"keys"`
+    } {keys}
+
+    inbody, err := json.Marshal(data)
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("DELETE", fmt.Sprintf("/v1/single_runtime/nodes/%s/labels", id), nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+// DrainNode stops scheduling on the node and asks the runtime to move the
+// apps running there to the remaining nodes.
+func (c *Client) DrainNode(id string) error {
+    if err := c.CordonNode(id); err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("POST", fmt.Sprintf("/v1/single_runtime/nodes/%s/actions/drain", id), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+// WaitForNodeConnected polls until the host that ran the GetImportCmd script
+// shows up as a connected node.
+func (c *Client) WaitForNodeConnected(hostname string, timeout time.Duration) (*Node, error) {
+    deadline := time.Now().Add(timeout)
+    for {
+        n, err := c.GetNodeByHostname(hostname)
+        if err != nil {
+            return nil, err
+        }
+        if n != nil && n.IsConnected {
+            return n, nil
+        }
+
+        if time.Now().After(deadline) {
+            return nil, fmt.Errorf("node %s not connected after %s", hostname, timeout)
+        }
+        time.Sleep(pollInterval)
+    }
+}
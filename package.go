@@ -15,10 +15,29 @@ type Package struct {
     TenantID       string   `json:"tenant_id"`
     ReleaseAccount int      `json:"releases_count"`
     LatestRelease  *Release `json:"latest_release"`
+    Description    string   `json:"description"`
+}
+
+// PackageOptions holds the package fields to update. Nil fields are left
+// unchanged.
+type PackageOptions struct {
+    IsPublic    *bool   `json:"is_public,omitempty"`
+    Description *string `json:"description,omitempty"`
 }
 
 type Release struct {
-    Name string `json:"release_name"`
+    Name        string      `json:"release_name"`
+    PackageID   string      `json:"package_id"`
+    Digest      string      `json:"digest"`
+    Size        int64       `json:"size"`
+    CreatedAt   int64       `json:"created_at"`
+    Layers      []*Layer    `json:"layers"`
+    ExposePorts []*PortInfo `json:"expose_ports"`
+}
+
+type Layer struct {
+    Digest string `json:"digest"`
+    Size   int64  `json:"size"`
 }
 
 type PortInfo struct {
@@ -89,3 +108,108 @@ func (c *Client) GetPortInfo(packageID, release string) ([]*PortInfo, error) {
 
     return result.Ports, nil
 }
+
+func (c *Client) GetPackage(id string) (*Package, error) {
+    status, body, _, err := c.do("GET", fmt.Sprintf("/v1/packages/%s", id), nil, nil, false)
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d", status)
+    }
+
+    result := new(Package)
+    if err := json.Unmarshal(body, result); err != nil {
+        return nil, err
+    }
+
+    return result, nil
+}
+
+func (c *Client) CreatePackage(name string, isPublic bool, description string) (string, error) {
+    type PackageT struct {
+        Name        string `json:"package_name"`
+        IsPublic    bool   `json:"is_public"`
+        Description string `json:"description"`
+    }
+
+    inbody, err := json.Marshal(&PackageT{Name: name, IsPublic: isPublic, Description: description})
+    if err != nil {
+        return "", err
+    }
+
+    status, outbody, _, err := c.do("POST", "/v1/packages", nil, inbody, false)
+    if err != nil {
+        return "", err
+    }
+    if status/100 != 2 {
+        return "", fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    result := struct {
+        PackageID string `json:"package_id"`
+    } {}
+    if err := json.Unmarshal(outbody, &result); err != nil {
+        return "", err
+    }
+
+    return result.PackageID, nil
+}
+
+func (c *Client) UpdatePackage(id string, opts *PackageOptions) error {
+    inbody, err := json.Marshal(opts)
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("PATCH", fmt.Sprintf("/v1/packages/%s", id), nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+func (c *Client) DeletePackage(id string) error {
+    status, _, _, err := c.do("DELETE", fmt.Sprintf("/v1/packages/%s", id), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d", status)
+    }
+
+    return nil
+}
+
+func (c *Client) GetRelease(packageID, release string) (*Release, error) {
+    status, body, _, err := c.do("GET", fmt.Sprintf("/v1/packages/%s/releases/%s", packageID, release), nil, nil, false)
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d", status)
+    }
+
+    result := new(Release)
+    if err := json.Unmarshal(body, result); err != nil {
+        return nil, err
+    }
+
+    return result, nil
+}
+
+func (c *Client) DeleteRelease(packageID, release string) error {
+    status, _, _, err := c.do("DELETE", fmt.Sprintf("/v1/packages/%s/releases/%s", packageID, release), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d", status)
+    }
+
+    return nil
+}
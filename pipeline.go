@@ -0,0 +1,253 @@
+package dao
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBuildTimeout  = 30 * time.Minute
+	defaultDeployTimeout = 10 * time.Minute
+)
+
+// Pipeline builds a branch of a buildflow and restages the given apps, and
+// the apps of the given stacks that run the buildflow package, with the
+// produced release.
+type Pipeline struct {
+	BuildflowID   string
+	Branch        string
+	Apps          []string
+	Stacks        []string
+	BuildTimeout  time.Duration
+	DeployTimeout time.Duration
+}
+
+type PipelineStep struct {
+	Name       string    `json:"name"`
+	Target     string    `json:"target"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+type PipelineReport struct {
+	BuildflowID string          `json:"buildflow_id"`
+	Branch      string          `json:"branch"`
+	BuildID     int             `json:"build_id"`
+	PackageID   string          `json:"package_id"`
+	Release     string          `json:"release"`
+	Succeeded   bool            `json:"succeeded"`
+	Steps       []*PipelineStep `json:"steps"`
+	StartedAt   time.Time       `json:"started_at"`
+	FinishedAt  time.Time       `json:"finished_at"`
+}
+
+// RunPipeline runs the pipeline steps in order and stops at the first
+// failure, marking the remaining steps as skipped. The report is returned
+// even when the run fails.
+func (c *Client) RunPipeline(p *Pipeline) (*PipelineReport, error) {
+	buildTimeout := p.BuildTimeout
+	if buildTimeout == 0 {
+		buildTimeout = defaultBuildTimeout
+	}
+	deployTimeout := p.DeployTimeout
+	if deployTimeout == 0 {
+		deployTimeout = defaultDeployTimeout
+	}
+
+	report := new(PipelineReport)
+	report.BuildflowID = p.BuildflowID
+	report.Branch = p.Branch
+	report.Steps = make([]*PipelineStep, 0)
+	report.StartedAt = time.Now()
+
+	var failed error = nil
+	run := func(name, target string, fn func() error) {
+		step := &PipelineStep{Name: name, Target: target, StartedAt: time.Now()}
+		report.Steps = append(report.Steps, step)
+
+		if failed != nil {
+			step.Status = "skipped"
+		} else if err := fn(); err != nil {
+			step.Status = "failed"
+			step.Error = err.Error()
+			failed = fmt.Errorf("%s %s: %s", name, target, err)
+		} else {
+			step.Status = "succeeded"
+		}
+		step.FinishedAt = time.Now()
+	}
+
+	run("build", p.Branch, func() error {
+		b, err := c.TriggerBuild(p.BuildflowID, &BuildOptions{Branch: p.Branch})
+		if err != nil {
+			return err
+		}
+		report.BuildID = b.ID
+
+		b, err = c.WaitForBuild(p.BuildflowID, b.ID, buildTimeout)
+		if err != nil {
+			return err
+		}
+		if !b.Succeeded() {
+			return fmt.Errorf("build %d is %s", b.ID, b.Status)
+		}
+		return nil
+	})
+
+	run("resolve", fmt.Sprintf("build %d", report.BuildID), func() error {
+		r, err := c.GetBuildArtifact(p.BuildflowID, report.BuildID)
+		if err != nil {
+			return err
+		}
+		report.PackageID = r.PackageID
+		report.Release = r.Name
+		return nil
+	})
+
+	for _, id := range p.Apps {
+		id := id
+		run("restage", "app "+id, func() error {
+			return c.restageAndWait(id, report.PackageID, report.Release, deployTimeout)
+		})
+	}
+
+	for _, id := range p.Stacks {
+		id := id
+		run("restage", "stack "+id, func() error {
+			s, err := c.GetStack(id)
+			if err != nil {
+				return err
+			}
+			if s == nil {
+				return fmt.Errorf("stack %s not found", id)
+			}
+
+			restaged := 0
+			for _, app := range s.Apps {
+				if app.PackageID != report.PackageID {
+					continue
+				}
+				if err := c.restageAndWait(app.ID, report.PackageID, report.Release, deployTimeout); err != nil {
+					return fmt.Errorf("app %s: %s", app.Name, err)
+				}
+				restaged++
+			}
+			if restaged == 0 {
+				return fmt.Errorf("stack %s has no app of package %s", s.Name, report.PackageID)
+			}
+			return nil
+		})
+	}
+
+	report.FinishedAt = time.Now()
+	report.Succeeded = failed == nil
+	return report, failed
+}
+
+func (c *Client) restageAndWait(id, packageID, release string, timeout time.Duration) error {
+	appID, err := c.RestageApp(id, packageID, release, true)
+	if err != nil {
+		return err
+	}
+	if appID == "" {
+		appID = id
+	}
+
+	return c.WaitForAppRelease(appID, release, timeout)
+}
+
+func (c *Client) WaitForBuild(buildflowID string, id int, timeout time.Duration) (*Build, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		b, err := c.GetBuild(buildflowID, id)
+		if err != nil {
+			return nil, err
+		}
+		if b == nil {
+			return nil, fmt.Errorf("build %d not found", id)
+		}
+		if b.Finished() {
+			return b, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("build %d still %s after %s", id, b.Status, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func (c *Client) WaitForAppRunning(id string, timeout time.Duration) error {
+	return c.waitForApp(id, "", timeout)
+}
+
+// WaitForAppRelease waits until the app has switched to the release and runs
+// it. Right after a restage the app may still report the old release as
+// running, which does not count.
+func (c *Client) WaitForAppRelease(id, release string, timeout time.Duration) error {
+	return c.waitForApp(id, release, timeout)
+}
+
+func (c *Client) waitForApp(id, release string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		state, current := "", release
+		if release != "" {
+			app, err := c.findApp(id)
+			if err != nil {
+				return err
+			}
+			if app == nil {
+				return fmt.Errorf("app %s not found", id)
+			}
+			current = app.ReleaseName
+		}
+
+		if current == release {
+			var err error = nil
+			if state, err = c.GetAppState(id); err != nil {
+				return err
+			}
+
+			switch strings.ToLower(state) {
+			case "running":
+				return nil
+			case "error", "failed", "crashed":
+				return fmt.Errorf("app %s is %s", id, state)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if current != release {
+				return fmt.Errorf("app %s still runs release %s instead of %s after %s", id, current, release, timeout)
+			}
+			return fmt.Errorf("app %s still %s after %s", id, state, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// findApp looks an app up among the apps and then among the apps of stacks.
+func (c *Client) findApp(id string) (*App, error) {
+	app, err := c.GetApp(id)
+	if err != nil || app != nil {
+		return app, err
+	}
+
+	stacks, err := c.ListStack()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stacks {
+		for _, a := range s.Apps {
+			if a.ID == id {
+				return a, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
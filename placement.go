@@ -0,0 +1,152 @@
+package dao
+
+import (
+    "fmt"
+)
+
+// Placement describes where single runtime apps and stacks may be scheduled.
+// Nodes and Labels narrow the candidate nodes, Cluster restricts them to one
+// cluster. Replicas instances are scheduled, spread across candidates when
+// Spread is set.
+type Placement struct {
+    Nodes    []string
+    Labels   map[string]string
+    Cluster  string
+    Spread   bool
+    Replicas int
+}
+
+func NodePlacement(nodeName string) *Placement {
+    return &Placement{Nodes: []string{nodeName}}
+}
+
+func (p *Placement) tags() []map[string]string {
+    base := make(map[string]string)
+    for k, v := range p.Labels {
+        base[k] = v
+    }
+    if p.Cluster != "" {
+        base["node_cluster_id"] = p.Cluster
+    }
+
+    if len(p.Nodes) == 0 {
+        return []map[string]string{base}
+    }
+
+    tags := make([]map[string]string, 0, len(p.Nodes))
+    for _, n := range p.Nodes {
+        tag := map[string]string{"name": n}
+        for k, v := range base {
+            tag[k] = v
+        }
+        tags = append(tags, tag)
+    }
+    return tags
+}
+
+func (p *Placement) instances() int {
+    if p.Replicas > 0 {
+        return p.Replicas
+    }
+    return 1
+}
+
+func (p *Placement) strategy() string {
+    if p.Spread {
+        return "spread"
+    }
+    return ""
+}
+
+// tagsMatchNode reports whether a node satisfies a list of placement tags.
+// Every key of a tag must match, any tag in the list is enough.
+func tagsMatchNode(tags []map[string]string, clusterID string, n *Node) bool {
+    for _, tag := range tags {
+        matched := true
+        for k, v := range tag {
+            switch k {
+            case "name":
+                matched = n.Name == v
+            case "node_cluster_id":
+                matched = clusterID == v
+            default:
+                matched = n.Labels[k] == v
+            }
+            if !matched {
+                break
+            }
+        }
+        if matched {
+            return true
+        }
+    }
+    return false
+}
+
+// ValidatePlacement checks the placement against the nodes known to
+// ListCluster and returns the nodes it may schedule on.
+func (c *Client) ValidatePlacement(p *Placement) ([]*Node, error) {
+    if p == nil {
+        return nil, fmt.Errorf("placement is empty")
+    }
+    if p.Replicas < 0 {
+        return nil, fmt.Errorf("replicas %d is invalid", p.Replicas)
+    }
+    if len(p.Nodes) == 0 && len(p.Labels) == 0 && p.Cluster == "" {
+        return nil, fmt.Errorf("placement has no node, label or cluster")
+    }
+
+    cs, err := c.ListCluster()
+    if err != nil {
+        return nil, err
+    }
+
+    if p.Cluster != "" {
+        found := false
+        for _, cluster := range cs {
+            if cluster.ID == p.Cluster {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return nil, fmt.Errorf("cluster %s not found", p.Cluster)
+        }
+    }
+
+    for _, name := range p.Nodes {
+        found := false
+        for _, cluster := range cs {
+            if p.Cluster != "" && cluster.ID != p.Cluster {
+                continue
+            }
+            for _, n := range cluster.Nodes {
+                if n.Name == name {
+                    found = true
+                }
+            }
+        }
+        if !found {
+            return nil, fmt.Errorf("node %s not found", name)
+        }
+    }
+
+    tags := p.tags()
+    nodes := make([]*Node, 0)
+    for _, cluster := range cs {
+        for _, n := range cluster.Nodes {
+            if tagsMatchNode(tags, cluster.ID, n) {
+                nodes = append(nodes, n)
+            }
+        }
+    }
+
+    if len(nodes) == 0 {
+        return nil, fmt.Errorf("no node matches placement")
+    }
+    if p.Spread && p.instances() > len(nodes) {
+        return nil, fmt.Errorf("cannot spread %d replicas across %d nodes", p.instances(), len(nodes))
+    }
+
+    return nodes, nil
+}
@@ -0,0 +1,565 @@
+package dao
+
+import (
+    "archive/tar"
+    "bytes"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const (
+    defaultChunkSize  = 5 << 20
+    maxUploadRetries  = 3
+    ociIndexMediaType = "application/vnd.oci.image.index.v1+json"
+    ociManifestType   = "application/vnd.oci.image.manifest.v1+json"
+)
+
+type Registry struct {
+    Host      string
+    Insecure  bool
+    ChunkSize int64
+
+    client *Client
+    tokens map[string]string
+}
+
+type Descriptor struct {
+    MediaType   string            `json:"mediaType"`
+    Digest      string            `json:"digest"`
+    Size        int64             `json:"size"`
+    Annotations map[string]string `json:"annotations,omitempty"`
+    Platform    *Platform         `json:"platform,omitempty"`
+}
+
+type Platform struct {
+    Architecture string `json:"architecture"`
+    OS           string `json:"os"`
+    Variant      string `json:"variant,omitempty"`
+}
+
+type Manifest struct {
+    SchemaVersion int           `json:"schemaVersion"`
+    MediaType     string        `json:"mediaType,omitempty"`
+    Config        *Descriptor   `json:"config,omitempty"`
+    Layers        []*Descriptor `json:"layers,omitempty"`
+    Manifests     []*Descriptor `json:"manifests,omitempty"`
+}
+
+func (c *Client) Registry() *Registry {
+    return &Registry{
+        Host:      c.RegistryHost,
+        ChunkSize: defaultChunkSize,
+        client:    c,
+        tokens:    make(map[string]string),
+    }
+}
+
+func (p *Package) Repository() string {
+    if p.Namespace == "" {
+        return p.Name
+    }
+    return p.Namespace + "/" + p.Name
+}
+
+// PushRelease pushes an OCI image layout tarball as a release of the package.
+func (r *Registry) PushRelease(p *Package, release, tarball string) (string, error) {
+    return r.PushImageTarball(tarball, p.Repository(), release)
+}
+
+// PushImageTarball pushes the image of an OCI image layout tarball, such as
+// the one produced by `docker buildx build --output type=oci`, to repo:tag
+// and returns the digest of the pushed manifest.
+func (r *Registry) PushImageTarball(tarball, repo, tag string) (string, error) {
+    dir, err := ioutil.TempDir("", "dao-image-")
+    if err != nil {
+        return "", err
+    }
+    defer os.RemoveAll(dir)
+
+    index, err := extractImageLayout(tarball, dir)
+    if err != nil {
+        return "", err
+    }
+    if len(index.Manifests) == 0 {
+        return "", fmt.Errorf("image %s has no manifest", tarball)
+    }
+
+    desc := index.Manifests[0]
+    for _, m := range index.Manifests {
+        if m.Annotations["org.opencontainers.image.ref.name"] == tag {
+            desc = m
+            break
+        }
+    }
+
+    return r.pushManifestTree(dir, repo, tag, desc)
+}
+
+func (r *Registry) pushManifestTree(dir, repo, ref string, desc *Descriptor) (string, error) {
+    raw, err := ioutil.ReadFile(blobPath(dir, desc.Digest))
+    if err != nil {
+        return "", err
+    }
+
+    m := new(Manifest)
+    if err := json.Unmarshal(raw, m); err != nil {
+        return "", err
+    }
+
+    children := make([]*Descriptor, 0)
+    if m.Config != nil {
+        children = append(children, m.Config)
+    }
+    children = append(children, m.Layers...)
+    for _, d := range children {
+        if err := r.pushBlobFile(repo, d, blobPath(dir, d.Digest)); err != nil {
+            return "", err
+        }
+    }
+    for _, d := range m.Manifests {
+        if _, err := r.pushManifestTree(dir, repo, d.Digest, d); err != nil {
+            return "", err
+        }
+    }
+
+    mediaType := desc.MediaType
+    if mediaType == "" {
+        mediaType = m.MediaType
+    }
+    if mediaType == "" {
+        mediaType = ociManifestType
+        if len(m.Manifests) > 0 {
+            mediaType = ociIndexMediaType
+        }
+    }
+
+    return r.PushManifest(repo, ref, mediaType, raw)
+}
+
+func (r *Registry) pushBlobFile(repo string, d *Descriptor, path string) error {
+    exists, err := r.BlobExists(repo, d.Digest)
+    if err != nil {
+        return err
+    }
+    if exists {
+        return nil
+    }
+
+    f, err := os.Open(path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    return r.PushBlob(repo, d.Digest, d.Size, f)
+}
+
+func (r *Registry) BlobExists(repo, digest string) (bool, error) {
+    res, err := r.request("HEAD", r.link(fmt.Sprintf("/v2/%s/blobs/%s", repo, digest)), nil, nil, pushScope(repo))
+    if err != nil {
+        return false, err
+    }
+    res.Body.Close()
+
+    switch {
+    case res.StatusCode == http.StatusNotFound:
+        return false, nil
+    case res.StatusCode/100 == 2:
+        return true, nil
+    default:
+        return false, fmt.Errorf("Status code is %d", res.StatusCode)
+    }
+}
+
+// PushBlob uploads a blob in chunks of ChunkSize. A failed chunk is retried
+// from the offset the registry reports for the upload session.
+func (r *Registry) PushBlob(repo, digest string, size int64, blob io.ReaderAt) error {
+    scope := pushScope(repo)
+    res, err := r.request("POST", r.link(fmt.Sprintf("/v2/%s/blobs/uploads/", repo)), nil, nil, scope)
+    if err != nil {
+        return err
+    }
+    outbody, _ := ioutil.ReadAll(res.Body)
+    res.Body.Close()
+    if res.StatusCode != http.StatusAccepted {
+        return fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+    }
+    location, err := r.location(res)
+    if err != nil {
+        return err
+    }
+
+    chunkSize := r.ChunkSize
+    if chunkSize <= 0 {
+        chunkSize = defaultChunkSize
+    }
+
+    var offset int64
+    retries := 0
+    for offset < size {
+        n := chunkSize
+        if size-offset < n {
+            n = size - offset
+        }
+        chunk := make([]byte, n)
+        if _, err := blob.ReadAt(chunk, offset); err != nil && err != io.EOF {
+            return err
+        }
+
+        header := map[string]string{
+            "Content-Type":  "application/octet-stream",
+            "Content-Range": fmt.Sprintf("%d-%d", offset, offset+n-1),
+        }
+        res, err := r.request("PATCH", location, header, chunk, scope)
+        if err == nil {
+            outbody, _ = ioutil.ReadAll(res.Body)
+            res.Body.Close()
+            if res.StatusCode == http.StatusAccepted {
+                if location, err = r.location(res); err != nil {
+                    return err
+                }
+                offset += n
+                retries = 0
+                continue
+            }
+            if res.StatusCode/100 == 4 && res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+                return fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+            }
+            err = fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+        }
+
+        retries++
+        if retries > maxUploadRetries {
+            return err
+        }
+        if offset, location, err = r.uploadStatus(location, scope); err != nil {
+            return err
+        }
+    }
+
+    res, err = r.request("PUT", addQuery(location, "digest", digest), nil, nil, scope)
+    if err != nil {
+        return err
+    }
+    outbody, _ = ioutil.ReadAll(res.Body)
+    res.Body.Close()
+    if res.StatusCode != http.StatusCreated {
+        return fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+    }
+
+    return nil
+}
+
+func (r *Registry) uploadStatus(location, scope string) (int64, string, error) {
+    res, err := r.request("GET", location, nil, nil, scope)
+    if err != nil {
+        return 0, "", err
+    }
+    res.Body.Close()
+    if res.StatusCode != http.StatusNoContent {
+        return 0, "", fmt.Errorf("Status code is %d", res.StatusCode)
+    }
+
+    next, err := r.location(res)
+    if err != nil {
+        next = location
+    }
+
+    rng := res.Header.Get("Range")
+    if rng == "" {
+        return 0, next, nil
+    }
+    end, err := strconv.ParseInt(rng[strings.LastIndex(rng, "-")+1:], 10, 64)
+    if err != nil {
+        return 0, "", fmt.Errorf("invalid upload range %s", rng)
+    }
+
+    return end + 1, next, nil
+}
+
+func (r *Registry) PushManifest(repo, ref, mediaType string, manifest []byte) (string, error) {
+    header := map[string]string{"Content-Type": mediaType}
+    res, err := r.request("PUT", r.link(fmt.Sprintf("/v2/%s/manifests/%s", repo, ref)), header, manifest, pushScope(repo))
+    if err != nil {
+        return "", err
+    }
+    defer res.Body.Close()
+
+    outbody, err := ioutil.ReadAll(res.Body)
+    if err != nil {
+        return "", err
+    }
+    if res.StatusCode/100 != 2 {
+        return "", fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+    }
+
+    digest := res.Header.Get("Docker-Content-Digest")
+    if digest == "" {
+        digest = sha256Digest(manifest)
+    }
+    return digest, nil
+}
+
+// request sends a registry request with the bearer token cached for scope,
+// and fetches a new token when the registry answers with an auth challenge.
+func (r *Registry) request(method, link string, header map[string]string, body []byte, scope string) (*http.Response, error) {
+    client := &http.Client{Timeout: 5 * time.Minute}
+
+    send := func() (*http.Response, error) {
+        var reader io.Reader = nil
+        if body != nil {
+            reader = bytes.NewReader(body)
+        }
+
+        req, err := http.NewRequest(method, link, reader)
+        if err != nil {
+            return nil, err
+        }
+        for k, v := range header {
+            req.Header.Set(k, v)
+        }
+        if method == "GET" || method == "HEAD" {
+            req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+        }
+        if token := r.tokens[scope]; token != "" {
+            req.Header.Set("Authorization", "Bearer " + token)
+        }
+
+        return client.Do(req)
+    }
+
+    res, err := send()
+    if err != nil {
+        return nil, err
+    }
+    if res.StatusCode != http.StatusUnauthorized {
+        return res, nil
+    }
+
+    challenge := res.Header.Get("WWW-Authenticate")
+    res.Body.Close()
+
+    token, err := r.token(challenge, scope)
+    if err != nil {
+        return nil, err
+    }
+    r.tokens[scope] = token
+
+    return send()
+}
+
+// token exchanges the client AuthToken for a registry bearer token.
+func (r *Registry) token(challenge, scope string) (string, error) {
+    params := parseChallenge(challenge)
+    realm := params["realm"]
+    if realm == "" {
+        return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+    }
+
+    q := url.Values{}
+    if params["service"] != "" {
+        q.Set("service", params["service"])
+    }
+    q.Set("scope", scope)
+
+    req, err := http.NewRequest("GET", addQuery(realm, "", q.Encode()), nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("Authorization", r.client.token())
+
+    client := &http.Client{Timeout: 10 * time.Second}
+    res, err := client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer res.Body.Close()
+
+    outbody, err := ioutil.ReadAll(res.Body)
+    if err != nil {
+        return "", err
+    }
+    if res.StatusCode/100 != 2 {
+        return "", fmt.Errorf("Status code is %d, reason %s", res.StatusCode, outbody)
+    }
+
+    result := struct {
+        Token       string `json:"token"`
+        AccessToken string `json:"access_token"`
+    } {}
+    if err := json.Unmarshal(outbody, &result); err != nil {
+        return "", err
+    }
+
+    if result.Token != "" {
+        return result.Token, nil
+    }
+    return result.AccessToken, nil
+}
+
+func (r *Registry) link(path string) string {
+    scheme := "https"
+    if r.Insecure {
+        scheme = "http"
+    }
+    return fmt.Sprintf("%s://%s%s", scheme, r.Host, path)
+}
+
+func (r *Registry) location(res *http.Response) (string, error) {
+    loc := res.Header.Get("Location")
+    if loc == "" {
+        return "", fmt.Errorf("registry returned no upload location")
+    }
+
+    u, err := url.Parse(loc)
+    if err != nil {
+        return "", err
+    }
+    return res.Request.URL.ResolveReference(u).String(), nil
+}
+
+var manifestMediaTypes = []string{
+    ociManifestType,
+    ociIndexMediaType,
+    "application/vnd.docker.distribution.manifest.v2+json",
+    "application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+func pushScope(repo string) string {
+    return fmt.Sprintf("repository:%s:pull,push", repo)
+}
+
+func parseChallenge(challenge string) map[string]string {
+    params := make(map[string]string)
+    if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+        return params
+    }
+
+    rest := challenge[len("bearer "):]
+    for rest != "" {
+        eq := strings.Index(rest, "=")
+        if eq < 0 {
+            break
+        }
+        key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+        rest = rest[eq+1:]
+
+        var value string
+        if strings.HasPrefix(rest, `"`) {
+            end := strings.Index(rest[1:], `"`)
+            if end < 0 {
+                value, rest = rest[1:], ""
+            } else {
+                value, rest = rest[1:end+1], rest[end+2:]
+            }
+        } else if comma := strings.Index(rest, ","); comma >= 0 {
+            value, rest = rest[:comma], rest[comma:]
+        } else {
+            value, rest = rest, ""
+        }
+        params[key] = value
+        rest = strings.TrimLeft(rest, ", ")
+    }
+
+    return params
+}
+
+func addQuery(link, key, value string) string {
+    sep := "?"
+    if strings.Contains(link, "?") {
+        sep = "&"
+    }
+    if key == "" {
+        return link + sep + value
+    }
+    return link + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
+func sha256Digest(data []byte) string {
+    sum := sha256.Sum256(data)
+    return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func blobPath(dir, digest string) string {
+    return filepath.Join(dir, "blobs", strings.Replace(digest, ":", string(filepath.Separator), 1))
+}
+
+// extractImageLayout unpacks the blobs of an OCI image layout tarball into
+// dir, verifying their digests, and returns the parsed index.json.
+func extractImageLayout(tarball, dir string) (*Manifest, error) {
+    f, err := os.Open(tarball)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var index *Manifest = nil
+    tr := tar.NewReader(f)
+    for {
+        hdr, err := tr.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+        if hdr.Typeflag != tar.TypeReg {
+            continue
+        }
+
+        name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(hdr.Name)), "./")
+        switch {
+        case name == "index.json":
+            index = new(Manifest)
+            if err := json.NewDecoder(tr).Decode(index); err != nil {
+                return nil, err
+            }
+        case strings.HasPrefix(name, "blobs/"):
+            parts := strings.Split(name, "/")
+            if len(parts) != 3 || parts[1] != "sha256" {
+                continue
+            }
+            if err := extractBlob(tr, blobPath(dir, "sha256:" + parts[2]), parts[2]); err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    if index == nil {
+        return nil, fmt.Errorf("%s is not an OCI image layout, index.json missing", tarball)
+    }
+    return index, nil
+}
+
+func extractBlob(r io.Reader, path, hexDigest string) error {
+    if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+        return err
+    }
+
+    out, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+    defer out.Close()
+
+    h := sha256.New()
+    if _, err := io.Copy(io.MultiWriter(out, h), r); err != nil {
+        return err
+    }
+    if hex.EncodeToString(h.Sum(nil)) != hexDigest {
+        return fmt.Errorf("blob sha256:%s digest mismatch", hexDigest)
+    }
+
+    return nil
+}
@@ -0,0 +1,34 @@
+package dao
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+    tests := []struct {
+        challenge string
+        want      map[string]string
+    }{
+        {
+            `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:team/app:pull,push"`,
+            map[string]string{"realm": "https://auth.example.com/token", "service": "registry.example.com", "scope": "repository:team/app:pull,push"},
+        },
+        {
+            `bearer Realm="https://auth/token", Service=registry`,
+            map[string]string{"realm": "https://auth/token", "service": "registry"},
+        },
+        {
+            `Bearer realm=https://auth/token,service="unterminated`,
+            map[string]string{"realm": "https://auth/token", "service": "unterminated"},
+        },
+        {`Basic realm="registry"`, map[string]string{}},
+        {"", map[string]string{}},
+    }
+
+    for _, tt := range tests {
+        if got := parseChallenge(tt.challenge); !reflect.DeepEqual(got, tt.want) {
+            t.Errorf("parseChallenge(%q) = %v, want %v", tt.challenge, got, tt.want)
+        }
+    }
+}
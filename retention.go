@@ -0,0 +1,171 @@
+package dao
+
+import (
+    "fmt"
+    "io"
+    "regexp"
+    "sort"
+)
+
+// RetentionPolicy selects the releases of a package to keep. A release is
+// kept when any rule matches it; every other release is deleted.
+type RetentionPolicy struct {
+    KeepLast     int
+    KeepTags     []string
+    KeepDeployed bool
+}
+
+type RetentionDecision struct {
+    Release *Release `json:"release"`
+    Reason  string   `json:"reason"`
+}
+
+type RetentionPlan struct {
+    PackageID string               `json:"package_id"`
+    Keep      []*RetentionDecision `json:"keep"`
+    Delete    []*RetentionDecision `json:"delete"`
+}
+
+func (c *Client) PlanRetention(packageID string, policy *RetentionPolicy) (*RetentionPlan, error) {
+    if policy == nil || (policy.KeepLast <= 0 && len(policy.KeepTags) == 0 && !policy.KeepDeployed) {
+        return nil, fmt.Errorf("retention policy keeps no release")
+    }
+
+    patterns := make([]*regexp.Regexp, 0, len(policy.KeepTags))
+    for _, t := range policy.KeepTags {
+        re, err := regexp.Compile(t)
+        if err != nil {
+            return nil, err
+        }
+        patterns = append(patterns, re)
+    }
+
+    releases, err := c.ListPackageRelease(packageID)
+    if err != nil {
+        return nil, err
+    }
+
+    deployed := make(map[string]string)
+    if policy.KeepDeployed {
+        if deployed, err = c.deployedReleases(packageID); err != nil {
+            return nil, err
+        }
+    }
+
+    if policy.KeepLast > 0 {
+        if err := c.fillCreatedAt(packageID, releases); err != nil {
+            return nil, err
+        }
+    }
+
+    sorted := make([]*Release, len(releases))
+    copy(sorted, releases)
+    sort.SliceStable(sorted, func(i, j int) bool {
+        return sorted[i].CreatedAt > sorted[j].CreatedAt
+    })
+
+    plan := new(RetentionPlan)
+    plan.PackageID = packageID
+    plan.Keep = make([]*RetentionDecision, 0)
+    plan.Delete = make([]*RetentionDecision, 0)
+
+    for i, r := range sorted {
+        reason := ""
+        if i < policy.KeepLast {
+            reason = fmt.Sprintf("within last %d", policy.KeepLast)
+        }
+        for _, re := range patterns {
+            if reason == "" && re.MatchString(r.Name) {
+                reason = fmt.Sprintf("matches %s", re)
+            }
+        }
+        if app, ok := deployed[r.Name]; reason == "" && ok {
+            reason = fmt.Sprintf("deployed by app %s", app)
+        }
+
+        if reason != "" {
+            plan.Keep = append(plan.Keep, &RetentionDecision{Release: r, Reason: reason})
+        } else {
+            plan.Delete = append(plan.Delete, &RetentionDecision{Release: r, Reason: "no rule matched"})
+        }
+    }
+
+    return plan, nil
+}
+
+// fillCreatedAt fetches the creation time of the releases listed without
+// one. KeepLast ranks releases by it, so planning fails rather than guess
+// the order when the API does not report it.
+func (c *Client) fillCreatedAt(packageID string, releases []*Release) error {
+    for _, r := range releases {
+        if r.CreatedAt != 0 {
+            continue
+        }
+
+        full, err := c.GetRelease(packageID, r.Name)
+        if err != nil {
+            return err
+        }
+        if full.CreatedAt == 0 {
+            return fmt.Errorf("release %s has no creation time, cannot keep the last releases", r.Name)
+        }
+        r.CreatedAt = full.CreatedAt
+    }
+
+    return nil
+}
+
+func (c *Client) deployedReleases(packageID string) (map[string]string, error) {
+    apps, err := c.ListApp()
+    if err != nil {
+        return nil, err
+    }
+
+    stacks, err := c.ListStack()
+    if err != nil {
+        return nil, err
+    }
+    for _, s := range stacks {
+        apps = append(apps, s.Apps...)
+    }
+
+    // Apps not reporting their package would make every release look
+    // undeployed, so refuse rather than plan to delete what they run.
+    reported := false
+    deployed := make(map[string]string)
+    for _, app := range apps {
+        if app.PackageID != "" && app.ReleaseName != "" {
+            reported = true
+        }
+        if app.PackageID == packageID && app.ReleaseName != "" {
+            deployed[app.ReleaseName] = app.Name
+        }
+    }
+    if len(apps) > 0 && !reported {
+        return nil, fmt.Errorf("no app reports its package and release, cannot tell which releases are deployed")
+    }
+
+    return deployed, nil
+}
+
+// ApplyRetention deletes the releases of the plan, stopping at the first
+// failure.
+func (c *Client) ApplyRetention(plan *RetentionPlan) error {
+    for _, d := range plan.Delete {
+        if err := c.DeleteRelease(plan.PackageID, d.Release.Name); err != nil {
+            return fmt.Errorf("delete release %s: %s", d.Release.Name, err)
+        }
+    }
+
+    return nil
+}
+
+func (p *RetentionPlan) Print(w io.Writer) {
+    for _, d := range p.Keep {
+        fmt.Fprintf(w, "keep    %s (%s)\n", d.Release.Name, d.Reason)
+    }
+    for _, d := range p.Delete {
+        fmt.Fprintf(w, "delete  %s (%s)\n", d.Release.Name, d.Reason)
+    }
+    fmt.Fprintf(w, "%d kept, %d to delete\n", len(p.Keep), len(p.Delete))
+}
@@ -0,0 +1,151 @@
+package dao
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func TestPlanRetention(t *testing.T) {
+    releases := `{"releases":[
+        {"release_name":"v1.0.0","created_at":100},
+        {"release_name":"v1.2.0","created_at":300},
+        {"release_name":"nightly","created_at":500},
+        {"release_name":"v1.1.0","created_at":200},
+        {"release_name":"v0.9.0","created_at":50}]}`
+    apps := `{"apps":[
+        {"app_id":"a1","name":"web","package_id":"p1","release_name":"v1.0.0"},
+        {"app_id":"a2","name":"other","package_id":"p2","release_name":"v0.9.0"}]}`
+    stackApps := `{"stacks":[{"stack_id":"s1","apps":[
+        {"app_id":"a3","name":"worker","package_id":"p1","release_name":"v0.9.0"}]}]}`
+
+    tests := []struct {
+        name     string
+        releases string
+        apps     string
+        stacks   string
+        details  map[string]string
+        policy   *RetentionPolicy
+        keep     []string
+        delete   []string
+        err      string
+    }{
+        {
+            name:     "keep last",
+            releases: releases,
+            policy:   &RetentionPolicy{KeepLast: 2},
+            keep:     []string{"nightly: within last 2", "v1.2.0: within last 2"},
+            delete:   []string{"v1.1.0", "v1.0.0", "v0.9.0"},
+        },
+        {
+            name:     "keep tags",
+            releases: releases,
+            policy:   &RetentionPolicy{KeepLast: 1, KeepTags: []string{`^v1\.[12]\.`}},
+            keep:     []string{"nightly: within last 1", "v1.2.0: matches ^v1\\.[12]\\.", "v1.1.0: matches ^v1\\.[12]\\."},
+            delete:   []string{"v1.0.0", "v0.9.0"},
+        },
+        {
+            name:     "keep deployed by apps and stacks",
+            releases: releases,
+            apps:     apps,
+            stacks:   stackApps,
+            policy:   &RetentionPolicy{KeepDeployed: true},
+            keep:     []string{"v1.0.0: deployed by app web", "v0.9.0: deployed by app worker"},
+            delete:   []string{"nightly", "v1.2.0", "v1.1.0"},
+        },
+        {
+            name:     "creation time fetched when not listed",
+            releases: `{"releases":[{"release_name":"old"},{"release_name":"new","created_at":200}]}`,
+            details:  map[string]string{"old": `{"release_name":"old","created_at":100}`},
+            policy:   &RetentionPolicy{KeepLast: 1},
+            keep:     []string{"new: within last 1"},
+            delete:   []string{"old"},
+        },
+        {
+            name:     "creation time unknown",
+            releases: `{"releases":[{"release_name":"old"},{"release_name":"new","created_at":200}]}`,
+            details:  map[string]string{"old": `{"release_name":"old"}`},
+            policy:   &RetentionPolicy{KeepLast: 1},
+            err:      "release old has no creation time",
+        },
+        {
+            name:     "apps without package",
+            releases: releases,
+            apps:     `{"apps":[{"app_id":"a1","name":"web"}]}`,
+            policy:   &RetentionPolicy{KeepDeployed: true},
+            err:      "no app reports its package and release",
+        },
+        {
+            name:     "empty policy",
+            releases: releases,
+            policy:   &RetentionPolicy{},
+            err:      "keeps no release",
+        },
+        {
+            name:     "invalid tag pattern",
+            releases: releases,
+            policy:   &RetentionPolicy{KeepTags: []string{"("}},
+            err:      "error parsing regexp",
+        },
+    }
+
+    for _, tt := range tests {
+        srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            switch path := r.URL.Path; {
+            case path == "/v1/packages/p1/releases":
+                fmt.Fprint(w, tt.releases)
+            case strings.HasPrefix(path, "/v1/packages/p1/releases/"):
+                detail, ok := tt.details[strings.TrimPrefix(path, "/v1/packages/p1/releases/")]
+                if !ok {
+                    w.WriteHeader(http.StatusNotFound)
+                    return
+                }
+                fmt.Fprint(w, detail)
+            case path == "/v1/apps" && tt.apps != "":
+                fmt.Fprint(w, tt.apps)
+            case path == "/v1/apps":
+                fmt.Fprint(w, `{"apps":[]}`)
+            case path == "/v1/stacks" && tt.stacks != "":
+                fmt.Fprint(w, tt.stacks)
+            case path == "/v1/stacks":
+                fmt.Fprint(w, `{"stacks":[]}`)
+            default:
+                t.Errorf("%s: unexpected request %s %s", tt.name, r.Method, path)
+                w.WriteHeader(http.StatusNotFound)
+            }
+        }))
+
+        c := &Client{Host: strings.TrimPrefix(srv.URL, "http://"), AuthToken: "token"}
+        plan, err := c.PlanRetention("p1", tt.policy)
+        srv.Close()
+
+        if tt.err != "" {
+            if err == nil || !strings.Contains(err.Error(), tt.err) {
+                t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("%s: %s", tt.name, err)
+            continue
+        }
+
+        keep := make([]string, 0)
+        for _, d := range plan.Keep {
+            keep = append(keep, d.Release.Name+": "+d.Reason)
+        }
+        del := make([]string, 0)
+        for _, d := range plan.Delete {
+            del = append(del, d.Release.Name)
+        }
+        if !reflect.DeepEqual(keep, tt.keep) {
+            t.Errorf("%s: keep = %q, want %q", tt.name, keep, tt.keep)
+        }
+        if !reflect.DeepEqual(del, tt.delete) {
+            t.Errorf("%s: delete = %q, want %q", tt.name, del, tt.delete)
+        }
+    }
+}
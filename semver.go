@@ -0,0 +1,329 @@
+package dao
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+type Version struct {
+    Major int
+    Minor int
+    Patch int
+    Pre   string
+    Build string
+}
+
+// ParseVersion parses a release name as a semantic version. A leading "v"
+// and missing minor or patch numbers are accepted, so "v1.4" is 1.4.0.
+func ParseVersion(name string) (*Version, error) {
+    v, _, err := parseVersion(name)
+    return v, err
+}
+
+func parseVersion(name string) (*Version, int, error) {
+    s := strings.TrimPrefix(strings.TrimSpace(name), "v")
+    v := new(Version)
+
+    if plus := strings.Index(s, "+"); plus >= 0 {
+        v.Build = s[plus+1:]
+        s = s[:plus]
+    }
+    if dash := strings.Index(s, "-"); dash >= 0 {
+        v.Pre = s[dash+1:]
+        s = s[:dash]
+        if v.Pre == "" {
+            return nil, 0, fmt.Errorf("%s is not a semantic version", name)
+        }
+    }
+
+    parts := strings.Split(s, ".")
+    if len(parts) > 3 {
+        return nil, 0, fmt.Errorf("%s is not a semantic version", name)
+    }
+
+    nums := []*int{&v.Major, &v.Minor, &v.Patch}
+    for i, p := range parts {
+        n, err := strconv.Atoi(p)
+        if err != nil || n < 0 {
+            return nil, 0, fmt.Errorf("%s is not a semantic version", name)
+        }
+        *nums[i] = n
+    }
+
+    return v, len(parts), nil
+}
+
+func (v *Version) String() string {
+    s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+    if v.Pre != "" {
+        s += "-" + v.Pre
+    }
+    if v.Build != "" {
+        s += "+" + v.Build
+    }
+    return s
+}
+
+func (v *Version) Stable() bool {
+    return v.Pre == ""
+}
+
+// Compare returns -1, 0 or 1 following semver precedence. Build metadata is
+// ignored.
+func (v *Version) Compare(o *Version) int {
+    for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+        if d < 0 {
+            return -1
+        }
+        if d > 0 {
+            return 1
+        }
+    }
+
+    switch {
+    case v.Pre == o.Pre:
+        return 0
+    case v.Pre == "":
+        return 1
+    case o.Pre == "":
+        return -1
+    }
+
+    a, b := strings.Split(v.Pre, "."), strings.Split(o.Pre, ".")
+    for i := 0; i < len(a) && i < len(b); i++ {
+        if a[i] == b[i] {
+            continue
+        }
+        x, errx := strconv.Atoi(a[i])
+        y, erry := strconv.Atoi(b[i])
+        switch {
+        case errx == nil && erry == nil:
+            if x < y {
+                return -1
+            }
+            return 1
+        case errx == nil:
+            return -1
+        case erry == nil:
+            return 1
+        case a[i] < b[i]:
+            return -1
+        default:
+            return 1
+        }
+    }
+
+    switch {
+    case len(a) < len(b):
+        return -1
+    case len(a) > len(b):
+        return 1
+    }
+    return 0
+}
+
+// SortReleases sorts releases by ascending version. Releases whose name is
+// not a semantic version come first, in their original order.
+func SortReleases(releases []*Release) {
+    versions := make(map[*Release]*Version)
+    for _, r := range releases {
+        if v, err := ParseVersion(r.Name); err == nil {
+            versions[r] = v
+        }
+    }
+
+    sort.SliceStable(releases, func(i, j int) bool {
+        a, b := versions[releases[i]], versions[releases[j]]
+        switch {
+        case a == nil:
+            return b != nil
+        case b == nil:
+            return false
+        }
+        return a.Compare(b) < 0
+    })
+}
+
+type Constraint struct {
+    raw    string
+    latest bool
+    stable bool
+    pre    bool
+    checks []func(*Version) bool
+}
+
+// ParseConstraint parses "latest", "latest stable", "latest matching <c>",
+// exact versions, wildcards such as "*" or "1.4.x", tilde and caret ranges
+// and comparisons such as ">=1.2 <2". Only "latest" matches prereleases
+// unconditionally; other constraints match them only when they name a
+// prerelease themselves.
+func ParseConstraint(s string) (*Constraint, error) {
+    c := &Constraint{raw: s, checks: make([]func(*Version) bool, 0)}
+
+    expr := strings.ToLower(strings.TrimSpace(s))
+    switch expr {
+    case "latest":
+        c.latest = true
+        return c, nil
+    case "", "*", "x", "stable", "latest stable":
+        c.stable = true
+        return c, nil
+    }
+    expr = strings.TrimSpace(strings.TrimPrefix(expr, "latest matching "))
+
+    for _, term := range strings.Fields(strings.Replace(expr, ",", " ", -1)) {
+        if err := c.addTerm(term); err != nil {
+            return nil, err
+        }
+    }
+    if len(c.checks) == 0 {
+        return nil, fmt.Errorf("invalid version constraint %s", s)
+    }
+
+    return c, nil
+}
+
+func (c *Constraint) addTerm(term string) error {
+    op := ""
+    for _, o := range []string{">=", "<=", ">", "<", "=", "~", "^"} {
+        if strings.HasPrefix(term, o) {
+            op = o
+            term = term[len(o):]
+            break
+        }
+    }
+
+    wildcard := false
+    for _, w := range []string{".x", ".X", ".*"} {
+        for strings.HasSuffix(term, w) {
+            term = strings.TrimSuffix(term, w)
+            wildcard = true
+        }
+    }
+
+    v, n, err := parseVersion(term)
+    if err != nil {
+        return fmt.Errorf("invalid version constraint %s", c.raw)
+    }
+    if v.Pre != "" {
+        c.pre = true
+    }
+
+    cmp := func(f func(int) bool) {
+        c.checks = append(c.checks, func(x *Version) bool { return f(x.Compare(v)) })
+    }
+    upper := func(u *Version) {
+        c.checks = append(c.checks, func(x *Version) bool { return x.Compare(u) < 0 })
+    }
+
+    switch op {
+    case ">=":
+        cmp(func(r int) bool { return r >= 0 })
+    case "<=":
+        cmp(func(r int) bool { return r <= 0 })
+    case ">":
+        cmp(func(r int) bool { return r > 0 })
+    case "<":
+        cmp(func(r int) bool { return r < 0 })
+    case "~":
+        cmp(func(r int) bool { return r >= 0 })
+        if n == 1 {
+            upper(&Version{Major: v.Major + 1})
+        } else {
+            upper(&Version{Major: v.Major, Minor: v.Minor + 1})
+        }
+    case "^":
+        cmp(func(r int) bool { return r >= 0 })
+        switch {
+        case v.Major > 0 || n == 1:
+            upper(&Version{Major: v.Major + 1})
+        case v.Minor > 0 || n == 2:
+            upper(&Version{Major: 0, Minor: v.Minor + 1})
+        default:
+            upper(&Version{Major: 0, Minor: 0, Patch: v.Patch + 1})
+        }
+    default:
+        if !wildcard && n == 3 {
+            cmp(func(r int) bool { return r == 0 })
+            break
+        }
+        cmp(func(r int) bool { return r >= 0 })
+        if n == 1 {
+            upper(&Version{Major: v.Major + 1})
+        } else {
+            upper(&Version{Major: v.Major, Minor: v.Minor + 1})
+        }
+    }
+
+    return nil
+}
+
+func (c *Constraint) Match(v *Version) bool {
+    if c.latest {
+        return true
+    }
+    if !v.Stable() && (c.stable || !c.pre) {
+        return false
+    }
+    for _, check := range c.checks {
+        if !check(v) {
+            return false
+        }
+    }
+    return true
+}
+
+func (c *Constraint) String() string {
+    return c.raw
+}
+
+// ResolveRelease returns the highest release of the package satisfying the
+// constraint.
+func (c *Client) ResolveRelease(packageID, constraint string) (*Release, error) {
+    cons, err := ParseConstraint(constraint)
+    if err != nil {
+        return nil, err
+    }
+
+    releases, err := c.ListPackageRelease(packageID)
+    if err != nil {
+        return nil, err
+    }
+
+    var best *Release = nil
+    var bestVersion *Version = nil
+    for _, r := range releases {
+        v, err := ParseVersion(r.Name)
+        if err != nil || !cons.Match(v) {
+            continue
+        }
+        if bestVersion == nil || v.Compare(bestVersion) > 0 {
+            best, bestVersion = r, v
+        }
+    }
+
+    if best == nil {
+        return nil, fmt.Errorf("no release of package %s matches %s", packageID, constraint)
+    }
+    return best, nil
+}
+
+func (c *Client) CreateCfAppVersion(appName, pid, constraint, instanceType string, port int) (string, error) {
+    r, err := c.ResolveRelease(pid, constraint)
+    if err != nil {
+        return "", err
+    }
+
+    return c.CreateCfApp(appName, pid, r.Name, instanceType, port)
+}
+
+func (c *Client) RestageAppVersion(id, packageId, constraint string, startAfterStage bool) (string, error) {
+    r, err := c.ResolveRelease(packageId, constraint)
+    if err != nil {
+        return "", err
+    }
+
+    return c.RestageApp(id, packageId, r.Name, startAfterStage)
+}
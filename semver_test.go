@@ -0,0 +1,153 @@
+package dao
+
+import (
+    "testing"
+)
+
+func TestParseVersion(t *testing.T) {
+    tests := []struct {
+        name string
+        want string
+        ok   bool
+    }{
+        {"1.2.3", "1.2.3", true},
+        {"v1.4", "1.4.0", true},
+        {"2", "2.0.0", true},
+        {"1.0.0-rc.1+build.5", "1.0.0-rc.1+build.5", true},
+        {" v3.1.0 ", "3.1.0", true},
+        {"1.2.3.4", "", false},
+        {"1.x", "", false},
+        {"1.0.0-", "", false},
+        {"-1.0.0", "", false},
+        {"latest", "", false},
+    }
+
+    for _, tt := range tests {
+        v, err := ParseVersion(tt.name)
+        if (err == nil) != tt.ok {
+            t.Errorf("ParseVersion(%q) error = %v, want ok %v", tt.name, err, tt.ok)
+            continue
+        }
+        if tt.ok && v.String() != tt.want {
+            t.Errorf("ParseVersion(%q) = %s, want %s", tt.name, v, tt.want)
+        }
+    }
+}
+
+func TestVersionCompare(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"1.0.0", "1.0.0", 0},
+        {"1.0.0", "2.0.0", -1},
+        {"1.10.0", "1.9.0", 1},
+        {"1.0.1", "1.0.0", 1},
+        {"1.0.0-alpha", "1.0.0", -1},
+        {"1.0.0-alpha", "1.0.0-alpha.1", -1},
+        {"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+        {"1.0.0-beta.2", "1.0.0-beta.11", -1},
+        {"1.0.0-rc.1", "1.0.0-beta.11", 1},
+        {"1.0.0+build.1", "1.0.0+build.2", 0},
+    }
+
+    for _, tt := range tests {
+        a, _ := ParseVersion(tt.a)
+        b, _ := ParseVersion(tt.b)
+        if got := a.Compare(b); got != tt.want {
+            t.Errorf("%s.Compare(%s) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+        if got := b.Compare(a); got != -tt.want {
+            t.Errorf("%s.Compare(%s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+        }
+    }
+}
+
+func TestSortReleases(t *testing.T) {
+    names := []string{"1.10.0", "nightly", "1.2.0", "1.2.0-rc.1", "v1.9", "edge"}
+    releases := make([]*Release, 0, len(names))
+    for _, n := range names {
+        releases = append(releases, &Release{Name: n})
+    }
+
+    SortReleases(releases)
+
+    want := []string{"nightly", "edge", "1.2.0-rc.1", "1.2.0", "v1.9", "1.10.0"}
+    for i, r := range releases {
+        if r.Name != want[i] {
+            t.Fatalf("SortReleases order = %v, want %v", releaseNames(releases), want)
+        }
+    }
+}
+
+func TestConstraintMatch(t *testing.T) {
+    versions := []string{"0.9.0", "1.0.0", "1.4.0", "1.4.7", "1.5.0", "2.0.0-beta.1", "2.0.0", "2.3.4", "3.0.0-beta"}
+
+    tests := []struct {
+        constraint string
+        want       string
+    }{
+        {"latest", "3.0.0-beta"},
+        {"*", "2.3.4"},
+        {"", "2.3.4"},
+        {"stable", "2.3.4"},
+        {"latest stable", "2.3.4"},
+        {"1.4.0", "1.4.0"},
+        {"=1.4.0", "1.4.0"},
+        {"1.4.x", "1.4.7"},
+        {"1.x", "1.5.0"},
+        {"1", "1.5.0"},
+        {"~1.4", "1.4.7"},
+        {"~1", "1.5.0"},
+        {"^1.4.0", "1.5.0"},
+        {"^0.9", "0.9.0"},
+        {">=1.2 <2", "1.5.0"},
+        {">=1.2, <=1.4.7", "1.4.7"},
+        {">1.5.0", "2.3.4"},
+        {"<1", "0.9.0"},
+        {"latest matching 2.x", "2.3.4"},
+        {">=2.0.0-beta.1 <2.0.0", "2.0.0-beta.1"},
+        {"^3", ""},
+        {"4.x", ""},
+    }
+
+    for _, tt := range tests {
+        c, err := ParseConstraint(tt.constraint)
+        if err != nil {
+            t.Errorf("ParseConstraint(%q) error = %v", tt.constraint, err)
+            continue
+        }
+
+        var best *Version = nil
+        for _, s := range versions {
+            v, _ := ParseVersion(s)
+            if c.Match(v) && (best == nil || v.Compare(best) > 0) {
+                best = v
+            }
+        }
+
+        got := ""
+        if best != nil {
+            got = best.String()
+        }
+        if got != tt.want {
+            t.Errorf("constraint %q resolved to %q, want %q", tt.constraint, got, tt.want)
+        }
+    }
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+    for _, s := range []string{"latest matching", "~", ">=x", "1.2.3.4", "foo"} {
+        if _, err := ParseConstraint(s); err == nil {
+            t.Errorf("ParseConstraint(%q) succeeded, want an error", s)
+        }
+    }
+}
+
+func releaseNames(releases []*Release) []string {
+    names := make([]string, 0, len(releases))
+    for _, r := range releases {
+        names = append(names, r.Name)
+    }
+    return names
+}
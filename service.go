@@ -4,6 +4,7 @@ import (
     "encoding/json"
     "fmt"
     "strings"
+    "time"
 )
 
 type Service struct {
@@ -12,6 +13,32 @@ type Service struct {
     Category string `json:"category_name"`
 }
 
+type ServiceDetails struct {
+    ID          string              `json:"service_id"`
+    Name        string              `json:"service_name"`
+    Category    string              `json:"category_name"`
+    Description string              `json:"description"`
+    Versions    []string            `json:"versions"`
+    Plans       []*ServicePlan      `json:"plans"`
+    Parameters  []*ServiceParameter `json:"parameters"`
+}
+
+type ServicePlan struct {
+    InstanceType string  `json:"instance_type"`
+    Name         string  `json:"name"`
+    Description  string  `json:"description"`
+    Tier         string  `json:"pricing_tier"`
+    Price        float64 `json:"price"`
+}
+
+type ServiceParameter struct {
+    Name        string `json:"name"`
+    Description string `json:"description"`
+    Type        string `json:"type"`
+    Required    bool   `json:"required"`
+    Default     string `json:"default"`
+}
+
 type EnvVar struct {
     Name string `json:"env_var_name"`
     Value string `json:"env_var_value"`
@@ -23,6 +50,7 @@ type ServiceInstance struct {
     ServiceID string    `json:"service_id"`
     EnvVars   []*EnvVar `json:"env_vars"`
     Type      string    `json:"instance_type"`
+    Apps      []*App    `json:"bound_apps"`
 }
 
 func (c *Client) ListService() ([]*Service, error) {
@@ -59,6 +87,54 @@ func (c *Client) GetService(name string) (*Service, error) {
     return nil, nil
 }
 
+func (c *Client) GetServiceDetails(id string) (*ServiceDetails, error) {
+    status, body, _, err := c.do("GET", fmt.Sprintf("/v1/services/%s", id), nil, nil, false)
+    if err != nil {
+        return nil, err
+    }
+    if status/100 != 2 {
+        return nil, fmt.Errorf("Status code is %d", status)
+    }
+
+    result := new(ServiceDetails)
+    if err := json.Unmarshal(body, result); err != nil {
+        return nil, err
+    }
+
+    return result, nil
+}
+
+func (d *ServiceDetails) GetPlan(instanceType string) *ServicePlan {
+    for _, p := range d.Plans {
+        if strings.ToLower(p.InstanceType) == strings.ToLower(instanceType) {
+            return p
+        }
+    }
+
+    return nil
+}
+
+func (c *Client) ValidateServiceInstance(serviceID, name, serviceType string) error {
+    if strings.TrimSpace(name) == "" {
+        return fmt.Errorf("service instance name is empty")
+    }
+
+    d, err := c.GetServiceDetails(serviceID)
+    if err != nil {
+        return err
+    }
+
+    if len(d.Plans) > 0 && d.GetPlan(serviceType) == nil {
+        types := make([]string, 0, len(d.Plans))
+        for _, p := range d.Plans {
+            types = append(types, p.InstanceType)
+        }
+        return fmt.Errorf("service %s has no plan %s, available plans: %s", d.Name, serviceType, strings.Join(types, ", "))
+    }
+
+    return nil
+}
+
 func (c *Client) ListServiceInstance() ([]*ServiceInstance, error) {
     status, body, _, err := c.do("GET", "/v1/service-instances", nil, nil, false)
     if err != nil {
@@ -113,6 +189,16 @@ func (c *Client) CreateServiceInstance(serviceID, name, serviceType string) (str
     return result.InstanceID, nil
 }
 
+// CreateCheckedServiceInstance validates the name and type against the
+// service catalog before creating the instance.
+func (c *Client) CreateCheckedServiceInstance(serviceID, name, serviceType string) (string, error) {
+    if err := c.ValidateServiceInstance(serviceID, name, serviceType); err != nil {
+        return "", err
+    }
+
+    return c.CreateServiceInstance(serviceID, name, serviceType)
+}
+
 func (c *Client) GetServiceInstance(id string) (*ServiceInstance, error) {
     instances, err := c.ListServiceInstance()
     if err != nil {
@@ -139,3 +225,108 @@ func (c *Client) DeleteServiceInstance(id string) error {
 
     return nil
 }
+
+func (c *Client) GetServiceInstanceState(id string) (string, error) {
+    status, body, _, err := c.do("GET", fmt.Sprintf("/v1/service-instances/%s/state", id), nil, nil, false)
+    if err != nil {
+        return "", err
+    }
+    if status/100 != 2 {
+        return "", fmt.Errorf("Status code is %d", status)
+    }
+
+    result := struct {
+        State string `json:"state"`
+    } {}
+    if err := json.Unmarshal(body, &result); err != nil {
+        return "", err
+    }
+
+    return result.State, nil
+}
+
+func (c *Client) WaitForServiceInstanceReady(id string, timeout time.Duration) error {
+    deadline := time.Now().Add(timeout)
+    for {
+        state, err := c.GetServiceInstanceState(id)
+        if err != nil {
+            return err
+        }
+
+        switch strings.ToLower(state) {
+        case "running", "ready":
+            return nil
+        case "error", "failed":
+            return fmt.Errorf("service instance %s is %s", id, state)
+        }
+
+        if time.Now().After(deadline) {
+            return fmt.Errorf("service instance %s still %s after %s", id, state, timeout)
+        }
+        time.Sleep(pollInterval)
+    }
+}
+
+func (c *Client) UpdateServiceInstance(id, serviceType string) error {
+    type Instance struct {
+        Type string `json:"service_instance_type"`
+    }
+
+    ins, err := c.GetServiceInstance(id)
+    if err != nil {
+        return err
+    }
+    if ins == nil {
+        return fmt.Errorf("service instance %s not found", id)
+    }
+    if err := c.ValidateServiceInstance(ins.ServiceID, ins.Name, serviceType); err != nil {
+        return err
+    }
+
+    inbody, err := json.Marshal(&Instance{Type: serviceType})
+    if err != nil {
+        return err
+    }
+
+    status, outbody, _, err := c.do("PATCH", fmt.Sprintf("/v1/service-instances/%s", id), nil, inbody, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    return nil
+}
+
+// RotateServiceInstanceCredentials replaces the credentials exposed through
+// EnvVars. Bound apps only see the new values after a restart.
+func (c *Client) RotateServiceInstanceCredentials(id string, restartApps bool) error {
+    status, outbody, _, err := c.do("POST", fmt.Sprintf("/v1/service-instances/%s/actions/rotate_credentials", id), nil, nil, false)
+    if err != nil {
+        return err
+    }
+    if status/100 != 2 {
+        return fmt.Errorf("Status code is %d, reason %s", status, outbody)
+    }
+
+    if !restartApps {
+        return nil
+    }
+
+    ins, err := c.GetServiceInstance(id)
+    if err != nil {
+        return err
+    }
+    if ins == nil {
+        return fmt.Errorf("service instance %s not found", id)
+    }
+
+    for _, app := range ins.Apps {
+        if err := c.RestartApp(app.ID); err != nil {
+            return fmt.Errorf("restart app %s: %s", app.Name, err)
+        }
+    }
+
+    return nil
+}
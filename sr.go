@@ -3,7 +3,6 @@ package dao
 import (
     "encoding/json"
     "fmt"
-    "strings"
 )
 
 type Cluster struct {
@@ -15,12 +14,13 @@ type Cluster struct {
 }
 
 type Node struct {
-    ID           string   `json:"node_id"`
-    Addrs        []string `json:"node_addrs"`
-    Hostname     string   `json:"hostname"`
-    DockerStatus string   `json:"status"`
-    Name         string   `json:"node_name"`
-    IsConnected  bool     `json:"is_connected"`
+    ID           string            `json:"node_id"`
+    Addrs        []string          `json:"node_addrs"`
+    Hostname     string            `json:"hostname"`
+    DockerStatus string            `json:"status"`
+    Name         string            `json:"node_name"`
+    IsConnected  bool              `json:"is_connected"`
+    Labels       map[string]string `json:"labels"`
 }
 
 type SrEnv struct {
@@ -29,39 +29,64 @@ type SrEnv struct {
 }
 
 func (c *Client) GetUninstallCmd(os string) (string, error) {
-    switch strings.ToLower(os) {
-    case "ubuntu", "debian":
-        return "dpkg -r daomonit", nil
-    case "centos", "fedora":
-        return "rpm -e daomonit", nil
+    return c.GetUninstallCmdWithOptions(os, nil)
+}
+
+func (c *Client) GetUninstallCmdWithOptions(os string, opts *InstallOptions) (string, error) {
+    var cmd string
+    switch osFamily(os) {
+    case "deb":
+        cmd = "dpkg -r daomonit"
+    case "rpm":
+        cmd = "rpm -e daomonit"
+    case "suse":
+        cmd = "zypper -n remove daomonit"
+    case "apk":
+        cmd = "apk del daomonit"
     default:
         return "", fmt.Errorf("os type %s not supported", os)
     }
+
+    if opts != nil && opts.Sudo {
+        cmd = "sudo " + cmd
+    }
+    return cmd, nil
 }
 
 func (c *Client) GetImportCmd() (string, error) {
-    env, err := c.GetSrEnv()
+    cs, err := c.ListCluster()
     if err != nil {
         return "", err
     }
 
-    cs, err := c.ListCluster()
+    for _, cluster := range cs {
+        if cluster.IsDefault {
+            return c.importCmd(cluster)
+        }
+    }
+
+    return "", fmt.Errorf("no available cluster")
+}
+
+func (c *Client) GetImportCmdForCluster(clusterID string) (string, error) {
+    cluster, err := c.GetCluster(clusterID)
     if err != nil {
         return "", err
     }
+    if cluster == nil {
+        return "", fmt.Errorf("cluster %s not found", clusterID)
+    }
 
-    for _, c := range cs {
-        if c.IsDefault {
-            return fmt.Sprintf(
-                "curl -sSL %s/daomonit/install.sh | sh -s %s %s",
-                env.DaoGetUrl,
-                c.Token,
-                env.DaoKeeperUrl,
-            ), nil
-        }
+    return c.importCmd(cluster)
+}
+
+func (c *Client) importCmd(cluster *Cluster) (string, error) {
+    plan, err := c.installPlan(cluster, nil)
+    if err != nil {
+        return "", err
     }
 
-    return "", fmt.Errorf("no available cluster")
+    return plan.Command(), nil
 }
 
 func (c *Client) GetSrEnv() (*SrEnv, error) {
@@ -6,18 +6,40 @@ import (
 )
 
 type Stack struct {
-    ID   string `json:"stack_id"`
-    Name string `json:"name"`
-    Apps []*App `json:"apps"`
+    ID       string       `json:"stack_id"`
+    Name     string       `json:"name"`
+    Apps     []*App       `json:"apps"`
+    Metadata *AppMetadata `json:"metadata"`
+}
+
+func (s *Stack) tags() []map[string]string {
+    if s.Metadata == nil {
+        return nil
+    }
+    return s.Metadata.Tags
 }
 
 func (c *Client) CreateStack(stackName, nodeName, yml string) (string, error) {
+    return c.createStack(stackName, NodePlacement(nodeName), yml)
+}
+
+func (c *Client) CreateStackWithPlacement(stackName string, p *Placement, yml string) (string, error) {
+    if _, err := c.ValidatePlacement(p); err != nil {
+        return "", err
+    }
+
+    return c.createStack(stackName, p, yml)
+}
+
+func (c *Client) createStack(stackName string, p *Placement, yml string) (string, error) {
     type Options struct {
         Yml string `json:"compose_yml"`
     }
 
     type Metadata struct {
         Tags []map[string]string `json:"tags"`
+        Strategy string `json:"scheduling_strategy,omitempty"`
+        Instances int `json:"instances"`
     }
 
     type StackT struct {
@@ -28,7 +50,9 @@ func (c *Client) CreateStack(stackName, nodeName, yml string) (string, error) {
     }
 
     m := new(Metadata)
-    m.Tags = []map[string]string{{"name": nodeName}}
+    m.Tags = p.tags()
+    m.Strategy = p.strategy()
+    m.Instances = p.instances()
 
     o := new(Options)
     o.Yml = yml
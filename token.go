@@ -0,0 +1,137 @@
+package dao
+
+import (
+    "fmt"
+    "time"
+)
+
+const tokenExpiryMargin = 30 * time.Second
+
+type accessToken struct {
+    Token        string `json:"access_token"`
+    ExpiresIn    int64  `json:"expires_in"`
+    RefreshToken string `json:"refresh_token"`
+}
+
+func (t *accessToken) toToken() *Token {
+    token := &Token{AccessToken: t.Token, RefreshToken: t.RefreshToken}
+    if t.ExpiresIn > 0 {
+        token.Expiry = time.Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+    }
+    return token
+}
+
+// SetCredentials stores the credentials used to log in again when the token
+// expires, for clients created with a token obtained elsewhere.
+func (c *Client) SetCredentials(user, passwd string) {
+    c.UseCredentials(&PasswordCredentials{Username: user, Password: passwd, Internal: true})
+}
+
+// UseCredentials replaces the client Credentials. The refresh token issued
+// by the previous ones is dropped, since only they can redeem it.
+func (c *Client) UseCredentials(cr Credentials) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.Credentials = cr
+    c.refreshToken = ""
+}
+
+// Authenticate obtains a new token from the client Credentials.
+func (c *Client) Authenticate() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.Credentials == nil {
+        return fmt.Errorf("client has no credentials")
+    }
+
+    t, err := c.Credentials.Authenticate(c)
+    if err != nil {
+        return err
+    }
+
+    c.setToken(t)
+    return nil
+}
+
+func (c *Client) token() string {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.AuthToken
+}
+
+// needsToken reports whether a token has to be obtained before a request,
+// because there is none yet or it is about to expire.
+func (c *Client) needsToken() bool {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if c.AuthToken == "" {
+        return c.Credentials != nil
+    }
+    return c.renewable() && !c.TokenExpiry.IsZero() && time.Now().Add(tokenExpiryMargin).After(c.TokenExpiry)
+}
+
+func (c *Client) canRefresh() bool {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    return c.renewable()
+}
+
+// renewable must be called with c.mu held. A static token cannot be renewed,
+// so a 401 with it is returned to the caller as is.
+func (c *Client) renewable() bool {
+    if c.refreshToken != "" {
+        return true
+    }
+    if _, static := c.Credentials.(StaticToken); static {
+        return false
+    }
+    return c.Credentials != nil
+}
+
+// setToken must be called with c.mu held.
+func (c *Client) setToken(t *Token) {
+    c.AuthToken = t.AccessToken
+    c.TokenExpiry = t.Expiry
+    c.refreshToken = t.RefreshToken
+}
+
+// refresh replaces the stale token, redeeming the refresh token with the
+// Credentials that issued it when they can, and authenticating again
+// otherwise. Concurrent callers are serialized, and a caller whose stale
+// token was already replaced returns without a new request.
+func (c *Client) refresh(stale string) error {
+    c.mu.Lock()
+    if c.AuthToken != stale {
+        c.mu.Unlock()
+        return nil
+    }
+
+    var t *Token = nil
+    var err error = fmt.Errorf("no credentials to renew the access token")
+    if r, ok := c.Credentials.(Refresher); ok && c.refreshToken != "" {
+        if t, err = r.Refresh(c, c.refreshToken); err == nil && t.RefreshToken == "" {
+            t.RefreshToken = c.refreshToken
+        }
+    }
+    if err != nil && c.Credentials != nil {
+        t, err = c.Credentials.Authenticate(c)
+    }
+    if err != nil {
+        c.mu.Unlock()
+        return fmt.Errorf("renew access token: %s", err)
+    }
+
+    c.setToken(t)
+    token, expiry, callback := c.AuthToken, c.TokenExpiry, c.OnTokenRefresh
+    c.mu.Unlock()
+
+    if callback != nil {
+        callback(token, expiry)
+    }
+    return nil
+}